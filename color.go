@@ -0,0 +1,259 @@
+package pb
+
+import (
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Attr is a SGR (Select Graphic Rendition) parameter of the terminal
+type Attr string
+
+const (
+	Reset     Attr = "0"
+	Bold      Attr = "1"
+	Faint     Attr = "2"
+	Italic    Attr = "3"
+	Underline Attr = "4"
+	Blink     Attr = "5"
+	Reverse   Attr = "7"
+)
+
+// Foreground colors
+const (
+	FgBlack   Attr = "30"
+	FgRed     Attr = "31"
+	FgGreen   Attr = "32"
+	FgYellow  Attr = "33"
+	FgBlue    Attr = "34"
+	FgMagenta Attr = "35"
+	FgCyan    Attr = "36"
+	FgWhite   Attr = "37"
+
+	FgHiBlack   Attr = "90"
+	FgHiRed     Attr = "91"
+	FgHiGreen   Attr = "92"
+	FgHiYellow  Attr = "93"
+	FgHiBlue    Attr = "94"
+	FgHiMagenta Attr = "95"
+	FgHiCyan    Attr = "96"
+	FgHiWhite   Attr = "97"
+)
+
+// Background colors
+const (
+	BgBlack   Attr = "40"
+	BgRed     Attr = "41"
+	BgGreen   Attr = "42"
+	BgYellow  Attr = "43"
+	BgBlue    Attr = "44"
+	BgMagenta Attr = "45"
+	BgCyan    Attr = "46"
+	BgWhite   Attr = "47"
+
+	BgHiBlack   Attr = "100"
+	BgHiRed     Attr = "101"
+	BgHiGreen   Attr = "102"
+	BgHiYellow  Attr = "103"
+	BgHiBlue    Attr = "104"
+	BgHiMagenta Attr = "105"
+	BgHiCyan    Attr = "106"
+	BgHiWhite   Attr = "107"
+)
+
+// Fg256 returns foreground color from the 256 colors palette
+func Fg256(n uint8) Attr {
+	return Attr("38;5;" + strconv.Itoa(int(n)))
+}
+
+// Bg256 returns background color from the 256 colors palette
+func Bg256(n uint8) Attr {
+	return Attr("48;5;" + strconv.Itoa(int(n)))
+}
+
+// FgRGB returns truecolor foreground color
+func FgRGB(r, g, b uint8) Attr {
+	return Attr("38;2;" + rgb(r, g, b))
+}
+
+// BgRGB returns truecolor background color
+func BgRGB(r, g, b uint8) Attr {
+	return Attr("48;2;" + rgb(r, g, b))
+}
+
+func rgb(r, g, b uint8) string {
+	return strconv.Itoa(int(r)) + ";" + strconv.Itoa(int(g)) + ";" + strconv.Itoa(int(b))
+}
+
+// Style is a set of attributes applied to a text
+type Style []Attr
+
+// Sprint returns the text with the style and reset sequence
+func (s Style) Sprint(text string) string {
+	if len(s) == 0 || text == "" {
+		return text
+	}
+	params := make([]string, len(s))
+	for i, a := range s {
+		params[i] = string(a)
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m" + text + "\x1b[" + string(Reset) + "m"
+}
+
+// Color mode of the bar
+type ColorMode int
+
+const (
+	// COLOR_AUTO disables colors when the output is not a terminal or NO_COLOR is set
+	COLOR_AUTO ColorMode = iota
+	COLOR_ALWAYS
+	COLOR_NEVER
+)
+
+// Set style of the element
+// Elements: fill and empty cells of the bar, prefix, postfix, message, counters, percent, speed,
+// etime, elapsed, spinner and the custom template elements
+// The "fail" style is used for the fill of the cancelled or failed bar and the error
+// Example: bar.SetStyle("fill", pb.FgGreen, pb.Bold).SetStyle("percent", pb.FgCyan)
+func (pb *ProgressBar) SetStyle(element string, attrs ...Attr) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	if pb.styles == nil {
+		pb.styles = make(map[string]Style)
+	}
+	pb.styles[element] = Style(attrs)
+	return pb
+}
+
+// Set color mode
+// bar.SetColorMode(COLOR_AUTO) - by default
+// bar.SetColorMode(COLOR_NEVER) - never print styles
+func (pb *ProgressBar) SetColorMode(mode ColorMode) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.ColorMode = mode
+	return pb
+}
+
+// isColor returns true if the styles are printed
+// The mode is resolved once, on start or on first print
+// Must be called with pb.mu held
+func (pb *ProgressBar) isColor() bool {
+	pb.colorOnce.Do(func() {
+		switch {
+		case pb.ColorMode == COLOR_ALWAYS:
+			pb.color = true
+		case pb.ColorMode == COLOR_NEVER, os.Getenv("NO_COLOR") != "":
+		default:
+			pb.color = isTerminalWriter(pb.printer())
+		}
+	})
+	return pb.color
+}
+
+// printer returns the writer printing the line of the bar: the output of the pool for pooled bars,
+// Output, the file of Terminal for Callback or stdout
+// Must be called with pb.mu held
+func (pb *ProgressBar) printer() io.Writer {
+	switch {
+	case pb.pooled && pb.poolOutput != nil:
+		return pb.poolOutput
+	case pb.pooled:
+		return os.Stdout
+	case pb.Output != nil:
+		return pb.Output
+	case pb.Callback != nil && pb.Terminal != nil:
+		return pb.Terminal.File()
+	}
+	return os.Stdout
+}
+
+// styled returns the text with the style of the element
+// Must be called with pb.mu held
+func (pb *ProgressBar) styled(element, text string) string {
+	if text == "" || !pb.isColor() {
+		return text
+	}
+	return pb.styles[element].Sprint(text)
+}
+
+// Threshold is a style of the bar fill from the percent of completion
+type Threshold struct {
+	Percent float64
+	Style   Style
+}
+
+// Set styles of the bar fill by the percent of completion, each one is used from its percent
+// Example: bar.SetThresholds(pb.Threshold{Percent: 0, Style: pb.Style{pb.FgRed}}, pb.Threshold{Percent: 70, Style: pb.Style{pb.FgGreen}})
+func (pb *ProgressBar) SetThresholds(thresholds ...Threshold) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.thresholds = append([]Threshold(nil), thresholds...)
+	sort.Stable(byPercent(pb.thresholds))
+	return pb
+}
+
+type byPercent []Threshold
+
+func (t byPercent) Len() int           { return len(t) }
+func (t byPercent) Less(i, j int) bool { return t[i].Percent < t[j].Percent }
+func (t byPercent) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+
+// RGB is a truecolor
+type RGB struct {
+	R, G, B uint8
+}
+
+// Set truecolor gradient of the bar fill, from the color of the first cell to the color of the last one
+// Example: bar.SetGradient(pb.RGB{R: 255}, pb.RGB{G: 255})
+func (pb *ProgressBar) SetGradient(from, to RGB) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.gradient = []RGB{from, to}
+	return pb
+}
+
+// fillText returns the fill cells of the bar with their style,
+// offset is the position of the first cell in the bar of the size cells
+// The "fail" style is used for the cancelled or failed bar, then the gradient, the thresholds and the "fill" style
+// Must be called with pb.mu held
+func (pb *ProgressBar) fillText(s *State, cells []string, offset, size int) string {
+	text := strings.Join(cells, "")
+	if text == "" || !pb.isColor() {
+		return text
+	}
+	fail, fill, gradient, thresholds := pb.styles["fail"], pb.styles["fill"], pb.gradient, pb.thresholds
+
+	switch {
+	case (s.Cancelled || s.Err != nil) && len(fail) > 0:
+		return fail.Sprint(text)
+	case len(gradient) == 2:
+		var out string
+		for i, c := range cells {
+			out += Style{gradientAt(gradient[0], gradient[1], offset+i, size)}.Sprint(c)
+		}
+		return out
+	case len(thresholds) > 0 && s.Total > 0:
+		percent := float64(s.Current) / float64(s.Total) * 100
+		for i := len(thresholds) - 1; i >= 0; i-- {
+			if percent >= thresholds[i].Percent {
+				return thresholds[i].Style.Sprint(text)
+			}
+		}
+	}
+	return fill.Sprint(text)
+}
+
+// gradientAt returns the color of the cell i of the bar of the size cells
+func gradientAt(from, to RGB, i, size int) Attr {
+	t := 0.0
+	if size > 1 {
+		t = float64(i) / float64(size-1)
+	}
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*t + 0.5)
+	}
+	return FgRGB(mix(from.R, to.R), mix(from.G, to.G), mix(from.B, to.B))
+}
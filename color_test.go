@@ -0,0 +1,94 @@
+package pb
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_Style(t *testing.T) {
+	s := Style{Bold, FgRGB(1, 2, 3)}.Sprint("text")
+	if expected := "\x1b[1;38;2;1;2;3mtext\x1b[0m"; s != expected {
+		t.Errorf("Expected %q, got %q", expected, s)
+	}
+	if s := (Style{}).Sprint("text"); s != "text" {
+		t.Errorf("Expected plain text, got %q", s)
+	}
+}
+
+func Test_SetStyle(t *testing.T) {
+	bar := New(10).SetWidth(40).SetColorMode(COLOR_ALWAYS)
+	bar.SetStyle("fill", FgGreen).SetStyle("empty", Faint).SetStyle("percent", FgCyan)
+	bar.NotPrint = true
+	bar.ShowTimeLeft = false
+	bar.Set(5)
+	bar.Update()
+	s := bar.String()
+	for _, e := range []string{"\x1b[32m", "\x1b[2m", "\x1b[36m50.00%\x1b[0m"} {
+		if !strings.Contains(s, e) {
+			t.Errorf("Expected %q in %q", e, s)
+		}
+	}
+	if w := escapeAwareRuneCountInString(s); w != 40 {
+		t.Errorf("Expected width 40, got %d: %q", w, s)
+	}
+}
+
+func Test_ColorModeAuto(t *testing.T) {
+	// buffer is not a terminal
+	bar := New(10).SetStyle("fill", FgGreen)
+	bar.Output = &bytes.Buffer{}
+	if bar.isColor() {
+		t.Error("Expected no colors for non terminal output")
+	}
+
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+	bar = New(10)
+	if bar.isColor() {
+		t.Error("Expected no colors with NO_COLOR")
+	}
+	if bar = New(10).SetColorMode(COLOR_ALWAYS); !bar.isColor() {
+		t.Error("Expected colors with COLOR_ALWAYS")
+	}
+}
+
+func Test_SetThresholds(t *testing.T) {
+	bar := New(10).SetWidth(40).SetColorMode(COLOR_ALWAYS)
+	bar.SetThresholds(Threshold{70, Style{FgGreen}}, Threshold{0, Style{FgRed}}, Threshold{30, Style{FgYellow}})
+	bar.SetStyle("fail", FgMagenta)
+	bar.NotPrint = true
+	for _, c := range []struct {
+		current int
+		color   string
+	}{{1, "\x1b[31m="}, {5, "\x1b[33m="}, {10, "\x1b[32m="}} {
+		bar.Set(c.current)
+		bar.Update()
+		if s := bar.String(); !strings.Contains(s, c.color) || escapeAwareRuneCountInString(s) != 40 {
+			t.Errorf("Expected %q in %q", c.color, s)
+		}
+	}
+
+	bar.isCancelled = true
+	bar.Set(9)
+	bar.Update()
+	if s := bar.String(); !strings.Contains(s, "\x1b[35m=") {
+		t.Errorf("Expected fail style in %q", s)
+	}
+}
+
+func Test_SetGradient(t *testing.T) {
+	bar := New(10).SetWidth(20).SetColorMode(COLOR_ALWAYS).SetGradient(RGB{255, 0, 0}, RGB{0, 255, 0})
+	bar.ShowPercent, bar.ShowCounters, bar.ShowTimeLeft = false, false, false
+	bar.NotPrint = true
+	bar.Set(10)
+	bar.Update()
+	s := bar.String()
+	if !strings.HasPrefix(s, "[\x1b[38;2;255;0;0m=\x1b[0m") || !strings.Contains(s, "\x1b[38;2;0;255;0m=\x1b[0m]") {
+		t.Errorf("Unexpected gradient %q", s)
+	}
+	if w := escapeAwareRuneCountInString(s); w != 20 {
+		t.Errorf("Expected width 20, got %d: %q", w, s)
+	}
+}
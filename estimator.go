@@ -0,0 +1,145 @@
+package pb
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Estimator calculates the speed of the bar, it's used for the speed and time left
+// Estimator must be safe for concurrent use
+type Estimator interface {
+	// Start resets the estimator with the value at the start of the work
+	Start(t time.Time, current int64)
+	// Update adds the value of the bar at time t
+	Update(t time.Time, current int64)
+	// Speed returns the estimated speed in units per second
+	Speed() float64
+}
+
+type sample struct {
+	t time.Time
+	v int64
+}
+
+// Create estimator with average speed since start (used by default)
+func NewAverageEstimator() Estimator {
+	return &averageEstimator{}
+}
+
+type averageEstimator struct {
+	mu          sync.Mutex
+	first, last sample
+}
+
+func (e *averageEstimator) Start(t time.Time, current int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.first = sample{t, current}
+	e.last = e.first
+}
+
+func (e *averageEstimator) Update(t time.Time, current int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.last = sample{t, current}
+}
+
+func (e *averageEstimator) Speed() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return rate(e.first, e.last)
+}
+
+// Create estimator with average speed over the last window of time
+func NewWindowEstimator(window time.Duration) Estimator {
+	return &windowEstimator{window: window}
+}
+
+type windowEstimator struct {
+	mu      sync.Mutex
+	window  time.Duration
+	samples []sample
+}
+
+func (e *windowEstimator) Start(t time.Time, current int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.samples = append(e.samples[:0], sample{t, current})
+}
+
+func (e *windowEstimator) Update(t time.Time, current int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.samples = append(e.samples, sample{t, current})
+	// keep one sample older than the window as the base of the calculation
+	var drop int
+	for drop < len(e.samples)-2 && t.Sub(e.samples[drop+1].t) >= e.window {
+		drop++
+	}
+	e.samples = append(e.samples[:0], e.samples[drop:]...)
+}
+
+func (e *windowEstimator) Speed() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.samples) < 2 {
+		return 0
+	}
+	return rate(e.samples[0], e.samples[len(e.samples)-1])
+}
+
+// Create estimator with exponentially weighted moving average of the speed
+// age is the time constant: older speeds lose weight as e^(-t/age)
+func NewEWMAEstimator(age time.Duration) Estimator {
+	return &ewmaEstimator{age: age}
+}
+
+type ewmaEstimator struct {
+	mu    sync.Mutex
+	age   time.Duration
+	last  sample
+	speed float64
+	ready bool
+}
+
+func (e *ewmaEstimator) Start(t time.Time, current int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.last = sample{t, current}
+	e.speed = 0
+	e.ready = false
+}
+
+func (e *ewmaEstimator) Update(t time.Time, current int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	dt := t.Sub(e.last.t)
+	if dt <= 0 {
+		return
+	}
+	speed := rate(e.last, sample{t, current})
+	if e.ready && e.age > 0 {
+		alpha := 1 - math.Exp(-float64(dt)/float64(e.age))
+		e.speed += alpha * (speed - e.speed)
+	} else {
+		e.speed = speed
+		e.ready = true
+	}
+	e.last = sample{t, current}
+}
+
+func (e *ewmaEstimator) Speed() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.speed
+}
+
+// rate returns units per second between two samples
+func rate(from, to sample) float64 {
+	dt := to.t.Sub(from.t)
+	if dt <= 0 || to.v <= from.v {
+		return 0
+	}
+	return float64(to.v-from.v) / dt.Seconds()
+}
@@ -0,0 +1,93 @@
+package pb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_AverageEstimator(t *testing.T) {
+	start := time.Now()
+	e := NewAverageEstimator()
+	e.Start(start, 100)
+	e.Update(start.Add(time.Second), 200)
+	e.Update(start.Add(4*time.Second), 500)
+	if speed := e.Speed(); speed != 100 {
+		t.Errorf("Expected speed 100, got %v", speed)
+	}
+}
+
+func Test_WindowEstimator(t *testing.T) {
+	start := time.Now()
+	e := NewWindowEstimator(2 * time.Second)
+	e.Start(start, 0)
+	// fast burst, then stall
+	e.Update(start.Add(time.Second), 1000)
+	e.Update(start.Add(2*time.Second), 1000)
+	e.Update(start.Add(3*time.Second), 1000)
+	e.Update(start.Add(4*time.Second), 1010)
+	if speed := e.Speed(); speed != 5 {
+		t.Errorf("Expected speed 5, got %v", speed)
+	}
+}
+
+func Test_EWMAEstimator(t *testing.T) {
+	start := time.Now()
+	e := NewEWMAEstimator(time.Second)
+	e.Start(start, 0)
+	e.Update(start.Add(time.Second), 100)
+	if speed := e.Speed(); speed != 100 {
+		t.Errorf("Expected first speed 100, got %v", speed)
+	}
+	// after a long stall the speed goes down to zero
+	e.Update(start.Add(time.Minute), 100)
+	if speed := e.Speed(); speed > 0.001 {
+		t.Errorf("Expected speed close to 0, got %v", speed)
+	}
+	// and recovers quickly
+	e.Update(start.Add(time.Minute+5*time.Second), 600)
+	if speed := e.Speed(); speed < 99 || speed > 100 {
+		t.Errorf("Expected speed close to 100, got %v", speed)
+	}
+}
+
+func Test_EstimatorTimeLeft(t *testing.T) {
+	bar := New(1000).SetWidth(80)
+	bar.ShowSpeed = true
+	s := &State{Current: 400, Total: 1000, Speed: 10, Units: U_NO}
+	if left := bar.timeLeftText(s); left != "1m0s" {
+		t.Errorf("Expected time left 1m0s, got %q", left)
+	}
+	if speed := bar.speedText(s); speed != "10/s" {
+		t.Errorf("Expected speed 10/s, got %q", speed)
+	}
+}
+
+func Test_EstimatorStall(t *testing.T) {
+	buf := &bytes.Buffer{}
+	bar := New(1000000).SetWidth(80).SetMode(MODE_TERMINAL).SetEstimator(NewWindowEstimator(100 * time.Millisecond))
+	bar.ShowSpeed = true
+	bar.ManualUpdate = true
+	bar.Output = buf
+	bar.Start()
+	for i := 0; i < 5; i++ {
+		time.Sleep(20 * time.Millisecond)
+		bar.Add(1000)
+		bar.Update()
+	}
+	if s := bar.String(); !strings.Contains(s, "/s") {
+		t.Fatalf("Expected speed, got %q", s)
+	}
+	// the value is not changed, the speed and time left go away with the window
+	for i := 0; i < 10; i++ {
+		time.Sleep(20 * time.Millisecond)
+		bar.Update()
+	}
+	if s := bar.String(); strings.Contains(s, "/s") {
+		t.Errorf("Expected no speed of the stalled bar, got %q", s)
+	}
+	if out := buf.String(); !strings.HasSuffix(out, bar.String()) {
+		t.Errorf("Expected the stalled bar printed, got %q", out)
+	}
+}
@@ -2,6 +2,9 @@ package pb
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -14,13 +17,119 @@ const (
 	U_BYTES
 	// U_DURATION units are formatted in a human readable way (3h14m15s)
 	U_DURATION
+	// U_BYTES_DEC units are formatted with decimal SI prefixes (B, kB, MB, ...)
+	U_BYTES_DEC
+	// U_BITS units are bytes formatted as bits with decimal prefixes (bit, Kbit, Mbit, ...),
+	// for network throughput
+	U_BITS
+	// U_METRIC units are plain counts with metric suffixes (999, 1.2k, 34M, ...)
+	U_METRIC
+
+	// the first value of units added with RegisterUnits
+	unitsCustom
+)
+
+// UnitFormatter formats values of custom units
+type UnitFormatter interface {
+	FormatUnits(n int64) string
+}
+
+// UnitFormatterFunc is an adapter to use ordinary functions as UnitFormatter
+type UnitFormatterFunc func(n int64) string
+
+func (f UnitFormatterFunc) FormatUnits(n int64) string {
+	return f(n)
+}
+
+type customUnits struct {
+	name string
+	f    UnitFormatter
+}
+
+var (
+	unitsMu  sync.RWMutex
+	unitsReg []customUnits
 )
 
+// Register custom units, the result can be used with Format(n).To(units) and bar.SetUnits(units)
+// Registering the same name again replaces the formatter and returns the same units
+// For example:
+// rows := pb.RegisterUnits("rows", pb.MetricUnits("rows")) // 1.2M rows
+func RegisterUnits(name string, f UnitFormatter) Units {
+	unitsMu.Lock()
+	defer unitsMu.Unlock()
+	for i, u := range unitsReg {
+		if u.name == name {
+			unitsReg[i].f = f
+			return unitsCustom + Units(i)
+		}
+	}
+	unitsReg = append(unitsReg, customUnits{name, f})
+	return unitsCustom + Units(len(unitsReg)-1)
+}
+
+// lookupUnits returns formatter of units added with RegisterUnits
+func lookupUnits(unit Units) (c customUnits, ok bool) {
+	unitsMu.RLock()
+	defer unitsMu.RUnlock()
+	if i := int(unit - unitsCustom); i >= 0 && i < len(unitsReg) {
+		return unitsReg[i], true
+	}
+	return
+}
+
+// String returns name of the units
+func (u Units) String() string {
+	switch u {
+	case U_NO:
+		return "no"
+	case U_BYTES:
+		return "bytes"
+	case U_DURATION:
+		return "duration"
+	case U_BYTES_DEC:
+		return "bytes_dec"
+	case U_BITS:
+		return "bits"
+	case U_METRIC:
+		return "metric"
+	}
+	if c, ok := lookupUnits(u); ok {
+		return c.name
+	}
+	return fmt.Sprintf("Units(%d)", int(u))
+}
+
+// MetricUnits returns formatter of counts with metric suffixes and the name of the units
+// Like a 1.2M rows, 34k records, 52 rows
+func MetricUnits(name string) UnitFormatter {
+	return UnitFormatterFunc(func(n int64) string {
+		return formatMetric(n) + " " + name
+	})
+}
+
 const (
 	KiB = 1024
 	MiB = 1048576
 	GiB = 1073741824
 	TiB = 1099511627776
+	PiB = 1125899906842624
+	EiB = 1152921504606846976
+)
+
+const (
+	KB = 1000
+	MB = 1000000
+	GB = 1000000000
+	TB = 1000000000000
+	PB = 1000000000000000
+	EB = 1000000000000000000
+)
+
+var (
+	bytesIEC = []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	bytesSI  = []string{"kB", "MB", "GB", "TB", "PB", "EB"}
+	bitsSI   = []string{"Kbit", "Mbit", "Gbit", "Tbit", "Pbit", "Ebit"}
 )
 
 func Format(i int64) *formatter {
@@ -53,10 +162,20 @@ func (f *formatter) String() (out string) {
 	switch f.unit {
 	case U_BYTES:
 		out = formatBytes(f.n)
+	case U_BYTES_DEC:
+		out = formatBytesDec(f.n)
+	case U_BITS:
+		out = formatBits(f.n)
 	case U_DURATION:
 		out = formatDuration(f.n)
+	case U_METRIC:
+		out = formatMetric(f.n)
 	default:
-		out = fmt.Sprintf(fmt.Sprintf("%%%dd", f.width), f.n)
+		if c, ok := lookupUnits(f.unit); ok {
+			out = c.f.FormatUnits(f.n)
+		} else {
+			out = fmt.Sprintf(fmt.Sprintf("%%%dd", f.width), f.n)
+		}
 	}
 	if f.perSec {
 		out += "/s"
@@ -65,20 +184,55 @@ func (f *formatter) String() (out string) {
 }
 
 // Convert bytes to human readable string. Like a 2 MiB, 64.2 KiB, 52 B
-func formatBytes(i int64) (result string) {
-	switch {
-	case i >= TiB:
-		result = fmt.Sprintf("%.02f TiB", float64(i)/TiB)
-	case i >= GiB:
-		result = fmt.Sprintf("%.02f GiB", float64(i)/GiB)
-	case i >= MiB:
-		result = fmt.Sprintf("%.02f MiB", float64(i)/MiB)
-	case i >= KiB:
-		result = fmt.Sprintf("%.02f KiB", float64(i)/KiB)
-	default:
-		result = fmt.Sprintf("%d B", i)
+func formatBytes(i int64) string {
+	return formatScaled(i, float64(i), KiB, bytesIEC, "B")
+}
+
+// Convert bytes to human readable string with SI prefixes. Like a 2 MB, 64.2 kB, 52 B
+func formatBytesDec(i int64) string {
+	return formatScaled(i, float64(i), KB, bytesSI, "B")
+}
+
+// Convert bytes to human readable bits. Like a 2 Mbit, 64.2 Kbit, 52 bit
+func formatBits(i int64) string {
+	return formatScaled(i*8, float64(i)*8, KB, bitsSI, "bit")
+}
+
+// formatScaled formats v in the largest unit not bigger than it,
+// units are the names of base^1, base^2, ... and n is printed as is with unit one
+func formatScaled(n int64, v float64, base float64, units []string, one string) string {
+	if v < base {
+		return fmt.Sprintf("%d %s", n, one)
 	}
-	return
+	i := 0
+	for v /= base; v >= base && i < len(units)-1; i++ {
+		v /= base
+	}
+	return fmt.Sprintf("%.02f %s", v, units[i])
+}
+
+var metricSuffixes = []string{"k", "M", "G", "T", "P", "E"}
+
+// Convert count to string with metric suffix. Like a 1.2M, 34k, 52
+func formatMetric(n int64) string {
+	if n < 0 {
+		// -n overflows for math.MinInt64, but its uint64 is right
+		return "-" + formatUintMetric(uint64(-n))
+	}
+	return formatUintMetric(uint64(n))
+}
+
+func formatUintMetric(n uint64) string {
+	if n < 1000 {
+		return strconv.FormatUint(n, 10)
+	}
+	v, i := float64(n)/1000, 0
+	// 999.96k is rounded to 1000.0k, so it must be 1M
+	for v >= 999.95 && i < len(metricSuffixes)-1 {
+		v /= 1000
+		i++
+	}
+	return strings.TrimSuffix(strconv.FormatFloat(v, 'f', 1, 64), ".0") + metricSuffixes[i]
 }
 
 func formatDuration(n int64) (result string) {
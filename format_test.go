@@ -2,6 +2,7 @@ package pb
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"testing"
 	"time"
@@ -37,6 +38,8 @@ func Test_CanFormatAsBytes(t *testing.T) {
 		{v: 3*MiB + 140*KiB, e: "3.14 MiB"},
 		{v: 2 * GiB, e: "2.00 GiB"},
 		{v: 2048 * GiB, e: "2.00 TiB"},
+		{v: 3 * PiB, e: "3.00 PiB"},
+		{v: 5 * EiB, e: "5.00 EiB"},
 	}
 
 	for _, input := range inputs {
@@ -47,6 +50,49 @@ func Test_CanFormatAsBytes(t *testing.T) {
 	}
 }
 
+func Test_CanFormatAsDecimalBytes(t *testing.T) {
+	inputs := []struct {
+		v int64
+		e string
+	}{
+		{v: 999, e: "999 B"},
+		{v: 1000, e: "1.00 kB"},
+		{v: 3*MB + 140*KB, e: "3.14 MB"},
+		{v: 2 * TB, e: "2.00 TB"},
+		{v: 1500 * TB, e: "1.50 PB"},
+		{v: 9 * EB, e: "9.00 EB"},
+	}
+
+	for _, input := range inputs {
+		actual := Format(input.v).To(U_BYTES_DEC).String()
+		if actual != input.e {
+			t.Error(fmt.Sprintf("Expected {%s} was {%s}", input.e, actual))
+		}
+	}
+}
+
+func Test_CanFormatAsBits(t *testing.T) {
+	inputs := []struct {
+		v int64
+		e string
+	}{
+		{v: 100, e: "800 bit"},
+		{v: 125, e: "1.00 Kbit"},
+		{v: 12500000, e: "100.00 Mbit"},
+		{v: 2 * EB, e: "16.00 Ebit"},
+	}
+
+	for _, input := range inputs {
+		actual := Format(input.v).To(U_BITS).String()
+		if actual != input.e {
+			t.Error(fmt.Sprintf("Expected {%s} was {%s}", input.e, actual))
+		}
+	}
+	if actual := Format(12500000).To(U_BITS).PerSec().String(); actual != "100.00 Mbit/s" {
+		t.Error(fmt.Sprintf("Expected {100.00 Mbit/s} was {%s}", actual))
+	}
+}
+
 func Test_CanFormatDuration(t *testing.T) {
 	value := 10 * time.Minute
 	expected := "10m0s"
@@ -64,3 +110,54 @@ func Test_DefaultUnitsWidth(t *testing.T) {
 		t.Error(fmt.Sprintf("Expected {%s} was {%s}", expected, actual))
 	}
 }
+
+func Test_CanFormatMetric(t *testing.T) {
+	inputs := []struct {
+		v int64
+		e string
+	}{
+		{v: 999, e: "999"},
+		{v: 1000, e: "1k"},
+		{v: 34000, e: "34k"},
+		{v: 1234567, e: "1.2M"},
+		{v: 999960, e: "1M"},
+		{v: 5000000000, e: "5G"},
+		{v: -1500, e: "-1.5k"},
+		{v: math.MaxInt64, e: "9.2E"},
+		{v: math.MinInt64, e: "-9.2E"},
+	}
+
+	for _, input := range inputs {
+		actual := Format(input.v).To(U_METRIC).String()
+		if actual != input.e {
+			t.Error(fmt.Sprintf("Expected {%s} was {%s}", input.e, actual))
+		}
+	}
+}
+
+func Test_RegisterUnits(t *testing.T) {
+	rows := RegisterUnits("test_rows", MetricUnits("rows"))
+	if actual := Format(1200000).To(rows).PerSec().String(); actual != "1.2M rows/s" {
+		t.Error(fmt.Sprintf("Expected {1.2M rows/s} was {%s}", actual))
+	}
+	if rows.String() != "test_rows" {
+		t.Error(fmt.Sprintf("Expected {test_rows} was {%s}", rows))
+	}
+
+	images := RegisterUnits("test_images", UnitFormatterFunc(func(n int64) string {
+		return fmt.Sprintf("%d img", n)
+	}))
+	if images == rows {
+		t.Error("Expected different units")
+	}
+	if actual := Format(3).To(images).String(); actual != "3 img" {
+		t.Error(fmt.Sprintf("Expected {3 img} was {%s}", actual))
+	}
+	// registering the same name replaces the formatter
+	if again := RegisterUnits("test_rows", MetricUnits("records")); again != rows {
+		t.Error("Expected the same units for the same name")
+	}
+	if actual := Format(34000).To(rows).String(); actual != "34k records" {
+		t.Error(fmt.Sprintf("Expected {34k records} was {%s}", actual))
+	}
+}
@@ -0,0 +1,125 @@
+package pb
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Create new group bar, its value and total are the sums of the children
+// They are updated on every refresh of the group bar
+func NewGroupBar(children ...*ProgressBar) *ProgressBar {
+	pb := New64(0)
+	pb.isGroup = true
+	pb.group = children
+	pb.syncGroup()
+	return pb
+}
+
+// setGroup replaces the children of the group bar
+func (pb *ProgressBar) setGroup(children []*ProgressBar) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.group = append(pb.group[:0], children...)
+}
+
+// addRemoved keeps the value and total of the child removed from the group bar
+func (pb *ProgressBar) addRemoved(child *ProgressBar) {
+	current, total := child.Get(), child.total()
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.removedCurrent += current
+	pb.removedTotal += total
+}
+
+// syncGroup sets value and total of the group bar from its children and the removed ones
+func (pb *ProgressBar) syncGroup() {
+	pb.mu.Lock()
+	if !pb.isGroup {
+		pb.mu.Unlock()
+		return
+	}
+	group := append([]*ProgressBar(nil), pb.group...)
+	current, total := pb.removedCurrent, pb.removedTotal
+	pb.mu.Unlock()
+
+	for _, bar := range group {
+		current += bar.Get()
+		total += bar.total()
+	}
+	pb.SetTotal64(total)
+	pb.Set64(current)
+}
+
+type childBar struct {
+	bar    *ProgressBar
+	weight float64
+}
+
+// Add child bar with the weight
+// The value of the parent is the weighted sum of the children's fractions scaled to
+// the parent total (100 if the total is not set). The parent is finished when all
+// the children are finished, it fails with the first error if any of them failed.
+// In a pool the children are printed under the parent.
+// Example: bar.AddChild(download, 3).AddChild(extract, 1).AddChild(compile, 6)
+func (pb *ProgressBar) AddChild(child *ProgressBar, weight float64) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	atomic.CompareAndSwapInt64(&pb.Total, 0, 100)
+	pb.children = append(pb.children, childBar{child, weight})
+	return pb
+}
+
+// Children returns the bars added with AddChild
+func (pb *ProgressBar) Children() []*ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	children := make([]*ProgressBar, len(pb.children))
+	for i, c := range pb.children {
+		children[i] = c.bar
+	}
+	return children
+}
+
+// syncChildren sets the value of the parent bar from its children
+func (pb *ProgressBar) syncChildren() {
+	pb.mu.Lock()
+	children := append([]childBar(nil), pb.children...)
+	pb.mu.Unlock()
+	if len(children) == 0 {
+		return
+	}
+
+	var sum, weights float64
+	var err error
+	finished := true
+	for _, c := range children {
+		c.bar.syncChildren()
+		sum += c.weight * c.bar.fraction()
+		weights += c.weight
+		finished = finished && c.bar.IsFinished()
+		if err == nil {
+			err = c.bar.Err()
+		}
+	}
+	if weights > 0 {
+		pb.Set64(int64(math.Floor(sum/weights*float64(pb.total()) + 0.5)))
+	}
+	switch {
+	case finished && err != nil:
+		pb.Fail(err)
+	case finished:
+		pb.Finish()
+	}
+}
+
+// fraction returns the done part of the bar from 0 to 1
+func (pb *ProgressBar) fraction() float64 {
+	total := pb.total()
+	switch {
+	case pb.IsFinished():
+		return 1
+	case total <= 0:
+		return 0
+	}
+	return math.Min(1, float64(pb.Get())/float64(total))
+}
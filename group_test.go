@@ -0,0 +1,80 @@
+package pb
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_GroupBar(t *testing.T) {
+	first, second := New(100), New(300)
+	group := NewGroupBar(first, second)
+	group.ManualUpdate = true
+	group.NotPrint = true
+	group.Start()
+	if group.Total != 400 {
+		t.Errorf("Expected total 400, got %d", group.Total)
+	}
+
+	first.Add(50)
+	second.Add(150)
+	group.Update()
+	if group.Get() != 200 {
+		t.Errorf("Expected value 200, got %d", group.Get())
+	}
+
+	// children can be changed
+	group.setGroup([]*ProgressBar{first})
+	group.Update()
+	if group.Get() != 50 || group.Total != 100 {
+		t.Errorf("Expected 50 / 100, got %d / %d", group.Get(), group.Total)
+	}
+}
+
+func Test_ChildBars(t *testing.T) {
+	download, compile := New(200), New(10)
+	parent := New(0).AddChild(download, 1).AddChild(compile, 3)
+	if parent.Total != 100 {
+		t.Errorf("Expected default total 100, got %d", parent.Total)
+	}
+	parent.ManualUpdate = true
+	parent.NotPrint = true
+	parent.Start()
+
+	download.Add(100)
+	parent.Update()
+	if parent.Get() != 13 {
+		t.Errorf("Expected value 13, got %d", parent.Get())
+	}
+
+	download.Add(100)
+	download.Finish()
+	compile.Add(5)
+	parent.Update()
+	if parent.Get() != 63 || parent.IsFinished() {
+		t.Errorf("Expected unfinished parent with value 63, got %d", parent.Get())
+	}
+
+	compile.Finish()
+	parent.Update()
+	if parent.Get() != 100 || !parent.IsFinished() {
+		t.Errorf("Expected finished parent with value 100, got %d", parent.Get())
+	}
+}
+
+func Test_ChildBarsFail(t *testing.T) {
+	first, second := New(10), New(10)
+	parent := New(0).AddChild(first, 1).AddChild(second, 1)
+	parent.NotPrint = true
+
+	err := errors.New("no space left")
+	second.Fail(err)
+	parent.Update()
+	if parent.IsFinished() {
+		t.Error("Parent must wait for all children")
+	}
+	first.Finish()
+	parent.Update()
+	if !parent.IsFailed() || parent.Err() != err {
+		t.Errorf("Expected failed parent, got %v", parent.Err())
+	}
+}
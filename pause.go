@@ -0,0 +1,44 @@
+package pb
+
+import "time"
+
+// Pause stops the clock of the bar, the paused time is not counted in the elapsed time,
+// speed and time left. The bar shows "paused" instead of the time left until Resume.
+// Example: bar.Pause(); waitForInput(); bar.Resume()
+func (pb *ProgressBar) Pause() *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	if !pb.isPaused && !pb.isFinish {
+		pb.isPaused = true
+		pb.pausedAt = time.Now()
+	}
+	return pb
+}
+
+// Resume continues the clock of the paused bar
+func (pb *ProgressBar) Resume() *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	if pb.isPaused {
+		pb.isPaused = false
+		pb.pausedTime += time.Since(pb.pausedAt)
+	}
+	return pb
+}
+
+// IsPaused returns true if the bar is paused
+func (pb *ProgressBar) IsPaused() bool {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	return pb.isPaused
+}
+
+// clock returns the time of the bar at now, it is the time without the pauses
+// since the start, so it stays the same while the bar is paused
+// Must be called with pb.mu held
+func (pb *ProgressBar) clock(now time.Time) time.Time {
+	if pb.isPaused {
+		now = pb.pausedAt
+	}
+	return now.Add(-pb.pausedTime)
+}
@@ -0,0 +1,69 @@
+package pb
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_PauseIndicator(t *testing.T) {
+	bar := New(100).SetWidth(60)
+	bar.NotPrint = true
+	bar.ManualUpdate = true
+	bar.ShowTimeLeft = false
+	bar.Start()
+	bar.Set(10)
+
+	bar.Pause()
+	bar.Update()
+	if s := bar.String(); !bar.IsPaused() || !strings.Contains(s, " paused") {
+		t.Errorf("Expected paused bar, got %q", s)
+	}
+	// the indicator is removed without a change of the value
+	bar.Resume()
+	bar.Update()
+	if s := bar.String(); bar.IsPaused() || strings.Contains(s, "paused") {
+		t.Errorf("Expected resumed bar, got %q", s)
+	}
+}
+
+func Test_PauseTimeAccounting(t *testing.T) {
+	bar := New(7200)
+	bar.NotPrint = true
+	bar.ManualUpdate = true
+	bar.Start()
+
+	// started 2 hours ago and paused for the last hour
+	start := time.Now().Add(-2 * time.Hour)
+	bar.mu.Lock()
+	bar.startTime = start
+	bar.Estimator.Start(start, 0)
+	bar.mu.Unlock()
+	bar.Set(3600)
+	bar.Pause()
+	bar.mu.Lock()
+	bar.pausedAt = time.Now().Add(-time.Hour)
+	bar.mu.Unlock()
+
+	bar.mu.Lock()
+	s := bar.state(bar.Get(), 60)
+	bar.mu.Unlock()
+	if s.Elapsed < 59*time.Minute || s.Elapsed > 61*time.Minute || !s.Paused {
+		t.Errorf("Expected elapsed 1h while paused, got %v", s.Elapsed)
+	}
+
+	bar.Resume()
+	bar.mu.Lock()
+	s = bar.state(bar.Get(), 60)
+	bar.mu.Unlock()
+	if s.Elapsed < 59*time.Minute || s.Elapsed > 61*time.Minute {
+		t.Errorf("Expected elapsed 1h after resume, got %v", s.Elapsed)
+	}
+	// 3600 in the active hour, the pause is not counted
+	if s.Speed < 0.99 || s.Speed > 1.01 {
+		t.Errorf("Expected speed 1/s, got %v", s.Speed)
+	}
+	if left := bar.timeLeftText(s); left != "1h0m0s" && left != "59m59s" {
+		t.Errorf("Expected 1h left, got %q", left)
+	}
+}
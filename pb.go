@@ -2,9 +2,13 @@
 package pb
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
+	"os"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -19,8 +23,29 @@ const (
 	// Default refresh rate - 200ms
 	DEFAULT_REFRESH_RATE = time.Millisecond * 200
 	FORMAT               = "[=>-]"
+	// Default interval between lines in MODE_LINES - 10s
+	DEFAULT_LINE_INTERVAL = time.Second * 10
+	// Default percent step between lines in MODE_LINES
+	DEFAULT_LINE_PERCENT = 10
+	// The message is truncated to keep at least MIN_BAR_WIDTH cells of the bar
+	MIN_BAR_WIDTH = 10
 )
 
+// Output mode of the bar
+type Mode int
+
+const (
+	// MODE_AUTO uses MODE_TERMINAL when the output is a terminal and MODE_LINES otherwise
+	MODE_AUTO Mode = iota
+	// MODE_TERMINAL redraws the bar in place with carriage return
+	MODE_TERMINAL
+	// MODE_LINES prints a plain line every LineInterval or LinePercent, for logs and CI
+	MODE_LINES
+)
+
+// ErrFailed is the error of the bar failed with nil error
+var ErrFailed = errors.New("failed")
+
 // DEPRECATED
 // variables for backward compatibility, from now do not work
 // use pb.Format and pb.SetRefreshRate
@@ -46,6 +71,9 @@ func New64(total int64) *ProgressBar {
 		ShowFinalTime: true,
 		Units:         U_NO,
 		ManualUpdate:  false,
+		Estimator:     NewAverageEstimator(),
+		LineInterval:  DEFAULT_LINE_INTERVAL,
+		LinePercent:   DEFAULT_LINE_PERCENT,
 		finish:        make(chan struct{}),
 		currentValue:  -1,
 	}
@@ -57,6 +85,11 @@ func StartNew(total int) *ProgressBar {
 	return New(total).Start()
 }
 
+// Create new object and start, the bar is cancelled when ctx is done
+func StartNewWithContext(ctx context.Context, total int) *ProgressBar {
+	return New(total).StartWithContext(ctx)
+}
+
 // Callback for custom output
 // For example:
 // bar.Callback = func(s string) {
@@ -68,6 +101,7 @@ type Callback func(out string)
 type ProgressBar struct {
 	current int64 // current must be first member of struct (https://code.google.com/p/go/issues/detail?id=5278)
 
+	// Total must be accessed atomically after start, use SetTotal64 and AddTotal64
 	Total                            int64
 	RefreshRate                      time.Duration
 	ShowPercent, ShowCounters        bool
@@ -81,49 +115,128 @@ type ProgressBar struct {
 	ForceWidth                       bool
 	ManualUpdate                     bool
 	AutoStat                         bool
+	Estimator                        Estimator
+
+	// Output mode, MODE_AUTO by default
+	// In MODE_LINES a line is printed when LineInterval has passed or
+	// progress has crossed the next LinePercent step, zero disables the check
+	Mode         Mode
+	LineInterval time.Duration
+	LinePercent  float64
+
+	// Color mode, COLOR_AUTO by default
+	ColorMode ColorMode
+
+	// Terminal of the bar for the width, the terminal of Output or StdTerminal by default
+	Terminal *Terminal
 
 	// Default width for the time box.
 	UnitsWidth   int
 	TimeBoxWidth int
 
-	finishOnce sync.Once //Guards isFinish
-	finish     chan struct{}
-	isFinish   bool
+	finishOnce  sync.Once //Guards isFinish
+	finish      chan struct{}
+	isFinish    bool
+	isCancelled bool
+	err         error
 
 	startTime    time.Time
+	isPaused     bool
+	pausedAt     time.Time
+	pausedTime   time.Duration // total time of the pauses since start
+	lastPaused   bool
 	startValue   int64
 	currentValue int64
+	totalValue   int64
 
 	prefix, postfix string
-
+	message         string
+	template        []templatePart
+
+	isGroup bool
+	group   []*ProgressBar
+	// value and total of the children removed from the group
+	removedCurrent, removedTotal int64
+	children                     []childBar
+	pooled                       bool
+	indent                       int
+	// Output of the pool printing the bar
+	poolOutput io.Writer
+	// terminal of Output, released on finish
+	outputTerminal outputTerminal
+
+	// mu guards the fields of the bar, printMu is held from render to print, so the prints keep the order of the renders
+	// printMu is locked before mu
 	mu        sync.Mutex
+	printMu   sync.Mutex
 	lastPrint string
 
+	styles     map[string]Style
+	thresholds []Threshold
+	gradient   []RGB
+	colorOnce  sync.Once
+	color      bool
+
+	sizeGen   int
+	lastWidth int
+
+	modeOnce        sync.Once
+	lineMode        bool
+	lastLineTime    time.Time
+	lastLinePercent float64
+	lastLinePaused  bool
+
 	BarStart string
 	BarEnd   string
 	Empty    string
 	Current  string
 	CurrentN string
 
+	// Cells of the partially filled position, see SetTheme
+	Partials []string
+
+	// Spinner frames, see SetSpinner
+	Spinner []string
+
 	AlwaysUpdate bool
 }
 
 // Start print
 func (pb *ProgressBar) Start() *ProgressBar {
+	pb.mu.Lock()
 	pb.startTime = time.Now()
+	pb.pausedTime, pb.pausedAt = 0, pb.startTime
 	pb.startValue = atomic.LoadInt64(&pb.current)
-	if pb.Total == 0 {
-		pb.ShowTimeLeft = false
-		pb.ShowPercent = false
-		pb.AutoStat = false
+	pb.isLineMode()
+	pb.isColor()
+	if pb.Estimator != nil {
+		pb.Estimator.Start(pb.startTime, pb.startValue)
 	}
-	if !pb.ManualUpdate {
+	manualUpdate := pb.ManualUpdate
+	pb.mu.Unlock()
+	if !manualUpdate {
 		pb.Update() // Initial printing of the bar before running the bar refresher.
 		go pb.refresher()
 	}
 	return pb
 }
 
+// Start print, when ctx is done the bar stops refreshing and prints the cancelled state
+func (pb *ProgressBar) StartWithContext(ctx context.Context) *ProgressBar {
+	pb.Start()
+	go pb.watch(ctx)
+	return pb
+}
+
+// watch cancels the bar when ctx is done
+func (pb *ProgressBar) watch(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		pb.cancel()
+	case <-pb.finish:
+	}
+}
+
 // Increment current value
 func (pb *ProgressBar) Increment() int {
 	return pb.Add(1)
@@ -135,6 +248,29 @@ func (pb *ProgressBar) Get() int64 {
 	return c
 }
 
+// total returns Total
+func (pb *ProgressBar) total() int64 {
+	return atomic.LoadInt64(&pb.Total)
+}
+
+// Set total, it can be changed while the bar is running
+// Zero total is unknown, the bar is animated until the total is set
+func (pb *ProgressBar) SetTotal(total int) *ProgressBar {
+	return pb.SetTotal64(int64(total))
+}
+
+// SetTotal64 sets the total as int64
+func (pb *ProgressBar) SetTotal64(total int64) *ProgressBar {
+	atomic.StoreInt64(&pb.Total, total)
+	return pb
+}
+
+// Add to total, for the total discovered incrementally
+// Example: filepath.Walk(root, func(path string, info os.FileInfo, err error) error { bar.AddTotal64(info.Size()); ... })
+func (pb *ProgressBar) AddTotal64(add int64) int64 {
+	return atomic.AddInt64(&pb.Total, add)
+}
+
 // Set current value
 func (pb *ProgressBar) Set(current int) *ProgressBar {
 	return pb.Set64(int64(current))
@@ -157,16 +293,31 @@ func (pb *ProgressBar) Add64(add int64) int64 {
 
 // Set prefix string
 func (pb *ProgressBar) Prefix(prefix string) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
 	pb.prefix = prefix
 	return pb
 }
 
 // Set postfix string
 func (pb *ProgressBar) Postfix(postfix string) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
 	pb.postfix = postfix
 	return pb
 }
 
+// Set status message, like the current file or stage
+// It is printed after the bar and truncated with an ellipsis when the line is too long,
+// in templates it's the {{message}} element
+// Example: bar.SetMessage("copying " + name)
+func (pb *ProgressBar) SetMessage(message string) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.message = message
+	return pb
+}
+
 // Set custom format for bar
 // Example: bar.Format("[=>_]")
 // Example: bar.Format("[\x00=\x00>\x00-\x00]") // \x00 is the delimiter
@@ -178,31 +329,77 @@ func (pb *ProgressBar) Format(format string) *ProgressBar {
 		formatEntries = strings.Split(format, "\x00")
 	}
 	if len(formatEntries) == 5 {
+		pb.mu.Lock()
+		defer pb.mu.Unlock()
 		pb.BarStart = formatEntries[0]
 		pb.BarEnd = formatEntries[4]
 		pb.Empty = formatEntries[3]
 		pb.Current = formatEntries[1]
 		pb.CurrentN = formatEntries[2]
+		pb.Partials = nil
 	}
 	return pb
 }
 
+// Set template for the bar line, it replaces the Show* options
+// Elements: prefix, postfix, message, counters, bar, percent, speed, etime (time left
+// or final time), elapsed, spinner and any element added with RegisterElement.
+// Element can have a width: {{percent 8}} pads on the left, {{percent -8}} on the right.
+// {{bar}} without width fills the rest of the line.
+// {{message}} is truncated to its width, without width it keeps MIN_BAR_WIDTH of the bars.
+// Example: bar.SetTemplate("{{prefix}} {{bar}} {{percent}} {{speed}} eta {{etime}}")
+func (pb *ProgressBar) SetTemplate(tmpl string) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.template = parseTemplate(tmpl)
+	return pb
+}
+
 // Set bar refresh rate
 func (pb *ProgressBar) SetRefreshRate(rate time.Duration) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
 	pb.RefreshRate = rate
 	return pb
 }
 
 // Set units
 // bar.SetUnits(U_NO) - by default
-// bar.SetUnits(U_BYTES) - for Mb, Kb, etc
+// bar.SetUnits(U_BYTES) - for MiB, KiB, etc
+// bar.SetUnits(U_BYTES_DEC) - for MB, kB, etc
+// bar.SetUnits(U_BITS) - for Mbit, Kbit, etc, the value is in bytes
 func (pb *ProgressBar) SetUnits(units Units) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
 	pb.Units = units
 	return pb
 }
 
+// Set output mode
+// bar.SetMode(MODE_AUTO) - by default
+// bar.SetMode(MODE_LINES) - print plain lines instead of redrawing the bar
+func (pb *ProgressBar) SetMode(mode Mode) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.Mode = mode
+	return pb
+}
+
+// Set estimator for the speed and time left
+// bar.SetEstimator(pb.NewAverageEstimator()) - by default
+// bar.SetEstimator(pb.NewWindowEstimator(time.Second * 10)) - speed over the last 10 seconds
+// bar.SetEstimator(pb.NewEWMAEstimator(time.Second * 5)) - moving average of the speed
+func (pb *ProgressBar) SetEstimator(e Estimator) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.Estimator = e
+	return pb
+}
+
 // Set max width, if width is bigger than terminal width, will be ignored
 func (pb *ProgressBar) SetMaxWidth(width int) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
 	pb.Width = width
 	pb.ForceWidth = false
 	return pb
@@ -210,6 +407,8 @@ func (pb *ProgressBar) SetMaxWidth(width int) *ProgressBar {
 
 // Set bar width
 func (pb *ProgressBar) SetWidth(width int) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
 	pb.Width = width
 	pb.ForceWidth = true
 	return pb
@@ -218,21 +417,72 @@ func (pb *ProgressBar) SetWidth(width int) *ProgressBar {
 // End print
 func (pb *ProgressBar) Finish() {
 	//Protect multiple calls
+	pb.finishOnce.Do(pb.finishBar)
+}
+
+// cancel ends print with cancelled state, if the bar is not finished yet
+func (pb *ProgressBar) cancel() {
 	pb.finishOnce.Do(func() {
-		close(pb.finish)
-		pb.write(atomic.LoadInt64(&pb.current))
 		pb.mu.Lock()
-		defer pb.mu.Unlock()
-		switch {
-		case pb.Output != nil:
-			fmt.Fprintln(pb.Output)
-		case !pb.NotPrint:
-			fmt.Println()
-		}
-		pb.isFinish = true
+		pb.isCancelled = true
+		pb.mu.Unlock()
+		pb.finishBar()
+	})
+}
+
+// Fail ends print with the failed state, if the bar is not finished yet
+// The final line has "failed" instead of the time left and the error instead of the message,
+// the bar is filled with the "fail" style
+// Example: if err != nil { bar.Fail(err) } else { bar.Finish() }
+func (pb *ProgressBar) Fail(err error) {
+	if err == nil {
+		err = ErrFailed
+	}
+	pb.finishOnce.Do(func() {
+		pb.mu.Lock()
+		pb.err = err
+		pb.mu.Unlock()
+		pb.finishBar()
 	})
 }
 
+// IsFailed returns true if the bar is ended with Fail
+func (pb *ProgressBar) IsFailed() bool {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	return pb.err != nil
+}
+
+// Err returns the error of the failed bar, nil if it is not failed
+func (pb *ProgressBar) Err() error {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	return pb.err
+}
+
+// finishBar prints the final state, must be called under finishOnce
+// The final line is printed under printMu, so no refresh started before it is printed after
+func (pb *ProgressBar) finishBar() {
+	pb.printMu.Lock()
+	defer pb.printMu.Unlock()
+	close(pb.finish)
+	pb.print(atomic.LoadInt64(&pb.current), true)
+	pb.mu.Lock()
+	pb.isFinish = true
+	pb.outputTerminal.release()
+	lineMode, output, notPrint := pb.isLineMode(), pb.Output, pb.NotPrint
+	pb.mu.Unlock()
+
+	switch {
+	case lineMode:
+		// the last line is already terminated
+	case output != nil:
+		fmt.Fprintln(output)
+	case !notPrint:
+		fmt.Println()
+	}
+}
+
 // IsFinished return boolean
 func (pb *ProgressBar) IsFinished() bool {
 	pb.mu.Lock()
@@ -243,154 +493,318 @@ func (pb *ProgressBar) IsFinished() bool {
 // End print and write string 'str'
 func (pb *ProgressBar) FinishPrint(str string) {
 	pb.Finish()
-	if pb.Output != nil {
-		fmt.Fprintln(pb.Output, str)
+	pb.mu.Lock()
+	output := pb.Output
+	pb.mu.Unlock()
+	if output != nil {
+		fmt.Fprintln(output, str)
 	} else {
 		fmt.Println(str)
 	}
 }
 
-// implement io.Writer
+// implement io.Writer as a counting sink
+// The data is discarded and len(p) is added to the bar,
+// use NewProxyWriter to pass the data to another writer
 func (pb *ProgressBar) Write(p []byte) (n int, err error) {
 	n = len(p)
 	pb.Add(n)
 	return
 }
 
-// implement io.Reader
-func (pb *ProgressBar) Read(p []byte) (n int, err error) {
-	n = len(p)
-	pb.Add(n)
-	return
+// implement io.ReaderFrom as a counting sink, so io.Copy(bar, r) counts the bytes
+// r is read until EOF, the data is discarded and the read bytes are added to the bar
+func (pb *ProgressBar) ReadFrom(r io.Reader) (n int64, err error) {
+	return io.Copy(ioutil.Discard, &countingReader{Reader: r, bar: pb})
 }
 
 // Create new proxy reader over bar
-// Takes io.Reader or io.ReadCloser
+// Takes io.Reader or io.ReadCloser, use WrapReader to keep io.Seeker and io.ReaderAt of r
 func (pb *ProgressBar) NewProxyReader(r io.Reader) *Reader {
-	return &Reader{r, pb}
+	return &Reader{Reader: r, bar: pb}
+}
+
+// Create new proxy reader over bar, it implements io.Seeker and io.ReaderAt when r does
+// Seek sets the bar to the new offset, ReadAt adds the read bytes without moving the offset
+// Example: http.ServeContent(w, req, name, modtime, bar.WrapReader(f).(io.ReadSeeker))
+func (pb *ProgressBar) WrapReader(r io.Reader) io.ReadCloser {
+	return wrapReader(r, pb)
 }
 
-func (pb *ProgressBar) write(current int64) {
+// Create new proxy writer over bar
+// Takes io.Writer, the proxy implements io.Closer, Flush() error (like bufio.Writer),
+// Flush() (like http.Flusher) and Sync() error (like os.File) when w does
+func (pb *ProgressBar) NewProxyWriter(w io.Writer) io.Writer {
+	return newProxyWriter(w, pb)
+}
+
+// write renders the bar and prints it if force is set or the text is changed,
+// like the speed and time left of the stalled bar, in MODE_LINES nextLine decides
+func (pb *ProgressBar) write(current int64, force bool) {
+	pb.printMu.Lock()
+	defer pb.printMu.Unlock()
+	pb.print(current, force)
+}
+
+// print renders and prints the bar, nothing is printed after the final line
+// Must be called with pb.printMu held
+func (pb *ProgressBar) print(current int64, force bool) {
 	width := pb.GetWidth()
+	pb.mu.Lock()
+	s := pb.state(current, width)
 
-	var percentBox, countersBox, timeLeftBox, speedBox, barBox, end, out string
+	var out, end string
+	if pb.template != nil {
+		out = pb.renderTemplate(pb.template, s)
+	} else {
+		out = pb.renderDefault(s)
+	}
+
+	// check len
+	if cl := escapeAwareRuneCountInString(out); cl < width {
+		end = strings.Repeat(" ", width-cl)
+	}
+
+	// and print!
+	changed := force || out+end != pb.lastPrint
+	pb.lastPrint = out + end
+	isFinish, lineMode := pb.isFinish, pb.isLineMode()
+	// the interval is checked on every refresh, the stalled bar prints a line too
+	printLine := lineMode && pb.nextLine(s)
+	output, callback, notPrint := pb.Output, pb.Callback, pb.NotPrint
+	pb.mu.Unlock()
 
-	// percents
-	if pb.ShowPercent {
-		var percent float64
-		if pb.Total > 0 {
-			percent = float64(current) / (float64(pb.Total) / float64(100))
+	switch {
+	case isFinish:
+		return
+	case lineMode:
+		if !printLine {
+			return
+		}
+		if output != nil {
+			fmt.Fprintln(output, out)
 		} else {
-			percent = float64(current) / float64(100)
+			fmt.Println(out)
 		}
-		percentBox = fmt.Sprintf(" %6.02f%%", percent)
+	case !changed:
+		return
+	case output != nil:
+		fmt.Fprint(output, pb.redraw(width)+out+end)
+	case callback != nil:
+		callback(out + end)
+	case !notPrint:
+		fmt.Print(pb.redraw(width) + out + end)
 	}
+}
 
-	// counters
-	if pb.ShowCounters {
-		current := Format(current).To(pb.Units).Width(pb.UnitsWidth)
-		if pb.Total > 0 {
-			total := Format(pb.Total).To(pb.Units).Width(pb.UnitsWidth)
-			countersBox = fmt.Sprintf(" %s / %s ", current, total)
-		} else {
-			countersBox = fmt.Sprintf(" %s / ? ", current)
+// isLineMode returns true if the bar prints plain lines
+// The mode is resolved once, on start or on first print
+// Must be called with pb.mu held
+func (pb *ProgressBar) isLineMode() bool {
+	pb.modeOnce.Do(func() {
+		switch {
+		case pb.Output == nil && (pb.Callback != nil || pb.NotPrint):
+			// not printed by the bar
+		case pb.Mode == MODE_LINES:
+			pb.lineMode = true
+		case pb.Mode == MODE_AUTO && pb.Output != nil:
+			pb.lineMode = !isTerminalWriter(pb.Output)
+		case pb.Mode == MODE_AUTO:
+			pb.lineMode = !isTerminalWriter(os.Stdout)
 		}
+	})
+	return pb.lineMode
+}
+
+// nextLine returns true when the next line must be printed in MODE_LINES
+// Must be called with pb.mu held
+func (pb *ProgressBar) nextLine(s *State) (ok bool) {
+	now := time.Now()
+	var percent float64
+	if s.Total > 0 {
+		percent = float64(s.Current) / float64(s.Total) * 100
+	}
+	switch {
+	case s.Finished, pb.lastLineTime.IsZero(), s.Paused != pb.lastLinePaused:
+		ok = true
+	case pb.LineInterval > 0 && now.Sub(pb.lastLineTime) >= pb.LineInterval:
+		ok = true
+	case pb.LinePercent > 0 && math.Floor(percent/pb.LinePercent) > math.Floor(pb.lastLinePercent/pb.LinePercent):
+		ok = true
+	}
+	if ok {
+		pb.lastLineTime = now
+		pb.lastLinePercent = percent
+		pb.lastLinePaused = s.Paused
 	}
+	return
+}
 
-	// time left
-	fromStart := time.Now().Sub(pb.startTime)
-	currentFromStart := current - pb.startValue
+// state returns snapshot of the bar for rendering
+// Must be called with pb.mu held
+func (pb *ProgressBar) state(current int64, width int) *State {
+	// the clock of the bar stops while it is paused
+	now := pb.clock(time.Now())
+	s := &State{
+		Current: current,
+		Total:   pb.total(),
+		Width:   width,
+		Elapsed: now.Sub(pb.startTime),
+		Units:   pb.Units,
+		Paused:  pb.isPaused,
+	}
+	// the bar which is not started has zero Tick, int64 of the ticks is narrowed to the non-negative int
+	if pb.RefreshRate > 0 && !pb.startTime.IsZero() {
+		s.Tick = int(int64(s.Elapsed/pb.RefreshRate) & math.MaxInt32)
+	}
+	if pb.Estimator != nil {
+		if !s.Paused {
+			pb.Estimator.Update(now, current)
+		}
+		s.Speed = pb.Estimator.Speed()
+	}
 	select {
 	case <-pb.finish:
-		if pb.ShowFinalTime {
-			var left time.Duration
-			left = (fromStart / time.Second) * time.Second
-			timeLeftBox = fmt.Sprintf(" %s", left.String())
-		}
+		s.Finished = true
 	default:
-		if pb.ShowTimeLeft && currentFromStart > 0 {
-			perEntry := fromStart / time.Duration(currentFromStart)
-			var left time.Duration
-			if pb.Total > 0 {
-				left = time.Duration(pb.Total-currentFromStart) * perEntry
-				left = (left / time.Second) * time.Second
-			} else {
-				left = time.Duration(currentFromStart) * perEntry
-				left = (left / time.Second) * time.Second
-			}
-			timeLeft := Format(int64(left)).To(U_DURATION).String()
-			timeLeftBox = fmt.Sprintf(" %s", timeLeft)
-		}
 	}
+	s.Cancelled = pb.isCancelled
+	s.Err = pb.err
+	return s
+}
 
-	if len(timeLeftBox) < pb.TimeBoxWidth {
-		timeLeftBox = fmt.Sprintf("%s%s", strings.Repeat(" ", pb.TimeBoxWidth-len(timeLeftBox)), timeLeftBox)
+// renderDefault draws the classic layout controlled by the Show* options
+func (pb *ProgressBar) renderDefault(s *State) string {
+	var percentBox, countersBox, timeLeftBox, speedBox, messageBox, barBox string
+	prefix, postfix := pb.styled("prefix", pb.prefix), pb.styled("postfix", pb.postfix)
+
+	// percents, hidden while the total is unknown
+	if pb.ShowPercent && s.Total > 0 {
+		percentBox = " " + padElement(pb.styled("percent", pb.percentText(s)), 7)
+	}
+
+	// counters
+	if pb.ShowCounters {
+		countersBox = " " + pb.styled("counters", pb.countersText(s)) + " "
 	}
 
+	// time left
+	if s.Cancelled || s.Err != nil || s.Paused || s.Finished && pb.ShowFinalTime || !s.Finished && pb.ShowTimeLeft {
+		if timeLeft := pb.timeLeftText(s); timeLeft != "" {
+			timeLeftBox = " " + pb.styled("etime", timeLeft)
+		}
+	}
+	timeLeftBox = padElement(timeLeftBox, pb.TimeBoxWidth)
+
 	// speed
-	if pb.ShowSpeed && currentFromStart > 0 {
-		fromStart := time.Now().Sub(pb.startTime)
-		speed := float64(currentFromStart) / (float64(fromStart) / float64(time.Second))
-		speedBox = " " + Format(int64(speed)).To(pb.Units).Width(pb.UnitsWidth).PerSec().String()
+	if pb.ShowSpeed {
+		if speed := pb.speedText(s); speed != "" {
+			speedBox = " " + pb.styled("speed", speed)
+		}
 	}
 
-	barWidth := escapeAwareRuneCountInString(countersBox + pb.BarStart + pb.BarEnd + percentBox + timeLeftBox + speedBox + pb.prefix + pb.postfix)
-	// bar
-	if pb.ShowBar {
-		size := width - barWidth
-		if size > 0 {
-			if pb.Total > 0 {
-				curCount := int(math.Ceil((float64(current) / float64(pb.Total)) * float64(size)))
-				emptCount := size - curCount
-				barBox = pb.BarStart
-				if emptCount < 0 {
-					emptCount = 0
-				}
-				if curCount > size {
-					curCount = size
-				}
-				if emptCount <= 0 {
-					barBox += strings.Repeat(pb.Current, curCount)
-				} else if curCount > 0 {
-					barBox += strings.Repeat(pb.Current, curCount-1) + pb.CurrentN
-				}
-				barBox += strings.Repeat(pb.Empty, emptCount) + pb.BarEnd
-			} else {
-				barBox = pb.BarStart
-				pos := size - int(current)%int(size)
-				if pos-1 > 0 {
-					barBox += strings.Repeat(pb.Empty, pos-1)
-				}
-				barBox += pb.Current
-				if size-pos-1 > 0 {
-					barBox += strings.Repeat(pb.Empty, size-pos-1)
-				}
-				barBox += pb.BarEnd
+	barWidth := escapeAwareRuneCountInString(countersBox + pb.BarStart + pb.BarEnd + percentBox + timeLeftBox + speedBox + prefix + postfix)
+
+	// message or error, truncated to keep the bar
+	if message, style := pb.messageText(s); message != "" {
+		if s.Width > 0 {
+			free := s.Width - barWidth - 1
+			if pb.ShowBar {
+				free -= MIN_BAR_WIDTH
 			}
+			message = truncate(message, free)
+		}
+		if message != "" {
+			messageBox = " " + pb.styled(style, message)
+			barWidth += escapeAwareRuneCountInString(messageBox)
 		}
 	}
+	// bar
+	switch {
+	case pb.ShowBar && s.Total <= 0 && len(pb.Spinner) > 0:
+		barBox = pb.styled("spinner", pb.spinnerText(s))
+	case pb.ShowBar:
+		barBox = pb.barBox(s, s.Width-barWidth)
+	}
 
-	// check len
-	out = pb.prefix + countersBox + barBox + percentBox + speedBox + timeLeftBox + pb.postfix
-	if cl := escapeAwareRuneCountInString(out); cl < width {
-		end = strings.Repeat(" ", width-cl)
+	return prefix + countersBox + barBox + percentBox + speedBox + timeLeftBox + postfix + messageBox
+}
+
+func (pb *ProgressBar) percentText(s *State) string {
+	if s.Total <= 0 {
+		return ""
 	}
+	percent := float64(s.Current) / (float64(s.Total) / float64(100))
+	return fmt.Sprintf("%.02f%%", percent)
+}
 
-	// and print!
-	pb.mu.Lock()
-	pb.lastPrint = out + end
-	isFinish := pb.isFinish
-	pb.mu.Unlock()
-	switch {
-	case isFinish:
+func (pb *ProgressBar) countersText(s *State) string {
+	current := Format(s.Current).To(s.Units).Width(pb.UnitsWidth)
+	if s.Total > 0 {
+		total := Format(s.Total).To(s.Units).Width(pb.UnitsWidth)
+		return fmt.Sprintf("%s / %s", current, total)
+	}
+	return fmt.Sprintf("%s / ?", current)
+}
+
+// timeLeftText returns estimated time left, or the total time for finished bar
+func (pb *ProgressBar) timeLeftText(s *State) string {
+	if s.Cancelled {
+		return "cancelled"
+	}
+	if s.Err != nil {
+		return "failed"
+	}
+	if s.Finished {
+		left := (s.Elapsed / time.Second) * time.Second
+		return left.String()
+	}
+	if s.Paused {
+		return "paused"
+	}
+	if s.Speed <= 0 || s.Total <= 0 {
+		return ""
+	}
+	left := time.Duration(float64(s.Total-s.Current) / s.Speed * float64(time.Second))
+	if left < 0 {
+		left = 0
+	}
+	left = (left / time.Second) * time.Second
+	return Format(int64(left)).To(U_DURATION).String()
+}
+
+// messageText returns the error of the failed bar or the message, and the style of the text
+func (pb *ProgressBar) messageText(s *State) (text, style string) {
+	if s.Err != nil {
+		return s.Err.Error(), "fail"
+	}
+	return pb.message, "message"
+}
+
+func (pb *ProgressBar) speedText(s *State) string {
+	if s.Speed <= 0 {
+		return ""
+	}
+	return Format(int64(s.Speed)).To(s.Units).Width(pb.UnitsWidth).PerSec().String()
+}
+
+// barBox draws the bar with size cells between BarStart and BarEnd
+func (pb *ProgressBar) barBox(s *State, size int) (barBox string) {
+	if size <= 0 {
 		return
-	case pb.Output != nil:
-		fmt.Fprint(pb.Output, "\r"+out+end)
-	case pb.Callback != nil:
-		pb.Callback(out + end)
-	case !pb.NotPrint:
-		fmt.Print("\r" + out + end)
 	}
+	if s.Total > 0 {
+		fill := pb.fillCells(s, size)
+		emptCount := size - len(fill)
+		barBox = pb.BarStart + pb.fillText(s, fill, 0, size)
+		barBox += pb.styled("empty", strings.Repeat(pb.Empty, emptCount)) + pb.BarEnd
+	} else {
+		// unknown total, the current position moves on every refresh
+		pos := size - 1 - s.Tick%size
+		barBox = pb.BarStart + pb.styled("empty", strings.Repeat(pb.Empty, pos)) + pb.fillText(s, []string{pb.Current}, pos, size)
+		barBox += pb.styled("empty", strings.Repeat(pb.Empty, size-pos-1)) + pb.BarEnd
+	}
+	return
 }
 
 // GetTerminalWidth - returns terminal width for all platforms.
@@ -398,35 +812,63 @@ func GetTerminalWidth() (int, error) {
 	return terminalWidth()
 }
 
+// GetTerminalHeight - returns terminal height for all platforms.
+func GetTerminalHeight() (int, error) {
+	return terminalHeight()
+}
+
 func (pb *ProgressBar) GetWidth() int {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	indent := pb.indent
 	if pb.ForceWidth {
-		return pb.Width
+		return pb.Width - indent
 	}
 
 	width := pb.Width
-	termWidth, _ := terminalWidth()
+	termWidth, _ := pb.term().Width()
 	if width == 0 || termWidth <= width {
 		width = termWidth
 	}
 
-	return width
+	return width - indent
+}
+
+// term returns Terminal, the terminal of Output if it is a terminal file or StdTerminal
+// Must be called with pb.mu held
+func (pb *ProgressBar) term() *Terminal {
+	if pb.Terminal != nil {
+		return pb.Terminal
+	}
+	return pb.outputTerminal.get(pb.Output)
 }
 
 // Write the current state of the progressbar
 func (pb *ProgressBar) Update() {
+	pb.syncGroup()
+	pb.syncChildren()
 	c := atomic.LoadInt64(&pb.current)
-	if pb.AlwaysUpdate || c != pb.currentValue {
-		pb.write(c)
-		pb.currentValue = c
-	}
-	if pb.AutoStat {
-		if c == 0 {
-			pb.startTime = time.Now()
-			pb.startValue = 0
-		} else if c >= pb.Total && pb.isFinish != true {
-			pb.Finish()
+	pb.mu.Lock()
+	// with unknown total the bar is animated on every refresh,
+	// otherwise it's rendered to update the estimator and printed if the text is changed
+	total := pb.total()
+	changed := pb.AlwaysUpdate || c != pb.currentValue || total != pb.totalValue || total <= 0 || pb.isPaused != pb.lastPaused
+	pb.currentValue, pb.totalValue, pb.lastPaused = c, total, pb.isPaused
+	autoStat, isFinish := pb.AutoStat, pb.isFinish
+	if autoStat && c == 0 {
+		pb.startTime = time.Now()
+		pb.pausedTime, pb.pausedAt = 0, pb.startTime
+		pb.startValue = 0
+		if pb.Estimator != nil {
+			pb.Estimator.Start(pb.startTime, 0)
 		}
 	}
+	pb.mu.Unlock()
+
+	pb.write(c, changed)
+	if autoStat && total > 0 && c >= total && !isFinish {
+		pb.Finish()
+	}
 }
 
 // String return the last bar print
@@ -436,13 +878,20 @@ func (pb *ProgressBar) String() string {
 	return pb.lastPrint
 }
 
+// refreshRate returns RefreshRate
+func (pb *ProgressBar) refreshRate() time.Duration {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	return pb.RefreshRate
+}
+
 // Internal loop for refreshing the progressbar
 func (pb *ProgressBar) refresher() {
 	for {
 		select {
 		case <-pb.finish:
 			return
-		case <-time.After(pb.RefreshRate):
+		case <-time.After(pb.refreshRate()):
 			pb.Update()
 		}
 	}
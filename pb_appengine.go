@@ -2,10 +2,38 @@
 
 package pb
 
-import "errors"
+import (
+	"errors"
+	"os"
+)
 
-// terminalWidth returns width of the terminal, which is not supported
+// Signals restoring the terminal state
+var terminateSignals = []os.Signal{os.Interrupt}
+
+type termState struct{}
+
+// openTerminal returns stdout, there is no terminal on appengine
+func openTerminal() *os.File {
+	return os.Stdout
+}
+
+// terminalSize returns size of the terminal, which is not supported
 // and should always failed on appengine classic which is a sandboxed PaaS.
-func terminalWidth() (int, error) {
-	return 0, errors.New("Not supported")
+func terminalSize(fd uintptr) (int, int, error) {
+	return 0, 0, errors.New("Not supported")
+}
+
+// isTerminal always returns false on appengine
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+// lockEcho is not supported on appengine
+func lockEcho(fd uintptr, old *termState) error {
+	return errors.New("Not supported")
+}
+
+// unlockEcho is not supported on appengine
+func unlockEcho(fd uintptr, old *termState) error {
+	return errors.New("Not supported")
 }
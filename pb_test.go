@@ -2,7 +2,11 @@ package pb
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"io"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -74,6 +78,85 @@ func Test_AutoStat(t *testing.T) {
 	bar.Finish()
 }
 
+func Test_SetTotalWhileRunning(t *testing.T) {
+	bar := New(0).SetWidth(40)
+	bar.NotPrint = true
+	bar.AutoStat = true
+	bar.Start()
+	bar.Set(5)
+	bar.Update()
+	if s := bar.String(); !strings.Contains(s, "5 / ?") || strings.Contains(s, "%") || bar.IsFinished() {
+		t.Errorf("Expected unknown total, got %q", s)
+	}
+
+	// the total is discovered incrementally
+	bar.AddTotal64(4)
+	bar.AddTotal64(6)
+	bar.Set(6)
+	bar.Update()
+	if s := bar.String(); !strings.Contains(s, "6 / 10") || !strings.Contains(s, "60.00%") {
+		t.Errorf("Expected known total, got %q", s)
+	}
+
+	bar.SetTotal64(20)
+	bar.Update()
+	if s := bar.String(); !strings.Contains(s, "6 / 20") || !strings.Contains(s, "30.00%") {
+		t.Errorf("Expected new total, got %q", s)
+	}
+	bar.Set(20)
+	bar.Update()
+	if !bar.IsFinished() {
+		t.Error("Expected finished bar with AutoStat")
+	}
+}
+
+func Test_SetMessage(t *testing.T) {
+	bar := New(10).SetWidth(40).SetMessage("long message here and more")
+	bar.NotPrint = true
+	bar.ShowTimeLeft = false
+	bar.Set(5)
+	bar.Update()
+	s := bar.String()
+	if !strings.HasSuffix(s, "% long messa…") || escapeAwareRuneCountInString(s) != 40 {
+		t.Errorf("Expected truncated message, got %q", s)
+	}
+	// the bar keeps MIN_BAR_WIDTH cells
+	if bar := s[strings.Index(s, "[")+1 : strings.Index(s, "]")]; len(bar) != MIN_BAR_WIDTH {
+		t.Errorf("Expected bar of %d cells, got %q", MIN_BAR_WIDTH, bar)
+	}
+}
+
+func Test_Fail(t *testing.T) {
+	buf := &bytes.Buffer{}
+	bar := New(100).SetWidth(60).SetMode(MODE_TERMINAL).SetMessage("file.iso")
+	bar.Output = buf
+	bar.Set(43)
+	err := errors.New("connection reset")
+	bar.Fail(err)
+	bar.Finish()
+
+	if !bar.IsFinished() || !bar.IsFailed() || bar.Err() != err {
+		t.Errorf("Expected failed bar, got %v", bar.Err())
+	}
+	if s := bar.String(); !strings.HasSuffix(strings.TrimRight(s, " "), "43.00% failed connection reset") {
+		t.Errorf("Expected error in the final line, got %q", s)
+	}
+	if !strings.HasSuffix(buf.String(), "\n") {
+		t.Errorf("Expected final new line, got %q", buf.String())
+	}
+
+	bar = New(10)
+	bar.NotPrint = true
+	if bar.Finish(); bar.IsFailed() || bar.Err() != nil {
+		t.Error("Finished bar must not be failed")
+	}
+	bar = New(10)
+	bar.NotPrint = true
+	if bar.Fail(nil); bar.Err() != ErrFailed {
+		t.Errorf("Expected ErrFailed, got %v", bar.Err())
+	}
+}
+
 func Test_Finish_PrintNewline(t *testing.T) {
 	bar := New(5)
 	buf := &bytes.Buffer{}
@@ -101,3 +184,112 @@ func Test_FinishPrint(t *testing.T) {
 		t.Errorf("Expected %q to have suffix %q", expected, actual)
 	}
 }
+
+func Test_LineMode(t *testing.T) {
+	bar := New(100).SetWidth(40).SetMode(MODE_LINES)
+	bar.LineInterval = time.Hour
+	bar.LinePercent = 50
+	buf := &bytes.Buffer{}
+	bar.Output = buf
+	bar.ManualUpdate = true
+	bar.Start()
+	for _, v := range []int{10, 20, 40, 60, 80} {
+		bar.Set(v)
+		bar.Update()
+	}
+	bar.Finish()
+
+	lines := strings.Split(buf.String(), "\n")
+	// 10%, 60% and the final line
+	if len(lines) != 4 || lines[3] != "" {
+		t.Fatalf("Expected 3 lines, got %q", buf.String())
+	}
+	for _, line := range lines[:3] {
+		if strings.Contains(line, "\r") {
+			t.Errorf("Unexpected carriage return in %q", line)
+		}
+	}
+	if !strings.HasPrefix(lines[1], " 60 / 100 ") {
+		t.Errorf("Unexpected second line %q", lines[1])
+	}
+}
+
+func Test_LineModeStalled(t *testing.T) {
+	bar := New(100).SetWidth(40).SetMode(MODE_LINES)
+	bar.LineInterval = 50 * time.Millisecond
+	buf := &bytes.Buffer{}
+	bar.Output = buf
+	bar.ManualUpdate = true
+	bar.Start()
+	bar.Set(10)
+	bar.Update()
+	// the value is not changed, a line is printed every LineInterval
+	for i := 0; i < 6; i++ {
+		time.Sleep(20 * time.Millisecond)
+		bar.Update()
+	}
+	if lines := strings.Count(buf.String(), " 10 / 100 "); lines < 2 {
+		t.Errorf("Expected lines of the stalled bar, got %q", buf.String())
+	}
+}
+
+func Test_AutoModeNotTerminal(t *testing.T) {
+	bar := New(100)
+	bar.Output = &bytes.Buffer{}
+	if !bar.isLineMode() {
+		t.Error("Expected MODE_LINES for non-terminal output")
+	}
+}
+
+func Test_StartNewWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bar := New(100).SetMode(MODE_TERMINAL)
+	buf := &syncBuffer{}
+	bar.Output = buf
+	bar.StartWithContext(ctx)
+	bar.Add(10)
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for !bar.IsFinished() {
+		if time.Now().After(deadline) {
+			t.Fatal("Bar was not finished after cancel")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !strings.Contains(buf.String(), " cancelled") {
+		t.Errorf("Expected cancelled state in %q", buf.String())
+	}
+	// Finish after cancel is a no-op
+	bar.Finish()
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func Test_CountingSink(t *testing.T) {
+	bar := New(100)
+	n, err := io.Copy(bar, strings.NewReader(strings.Repeat("x", 70)))
+	if n != 70 || err != nil || bar.Get() != 70 {
+		t.Errorf("Unexpected copy result: %d, %v, bar %d", n, err, bar.Get())
+	}
+	bar.Write(make([]byte, 30))
+	if bar.Get() != 100 {
+		t.Errorf("Expected bar value 100, got %d", bar.Get())
+	}
+}
@@ -3,15 +3,22 @@
 package pb
 
 import (
-	"errors"
 	"fmt"
 	"os"
-	"sync"
 	"syscall"
 	"unsafe"
 )
 
-var tty = os.Stdin
+// Signals restoring the terminal state
+var terminateSignals = []os.Signal{os.Interrupt}
+
+// termState is the console mode, GetConsoleMode writes a DWORD
+type termState uint32
+
+// openTerminal returns stdout, the console screen buffer
+func openTerminal() *os.File {
+	return os.Stdout
+}
 
 var (
 	kernel32 = syscall.NewLazyDLL("kernel32.dll")
@@ -68,14 +75,21 @@ type (
 	}
 )
 
-// terminalWidth returns width of the terminal.
-func terminalWidth() (width int, err error) {
+// terminalSize returns width of the terminal and height of its visible window.
+func terminalSize(fd uintptr) (width, height int, err error) {
 	var info consoleScreenBufferInfo
-	_, _, e := syscall.Syscall(procGetConsoleScreenBufferInfo.Addr(), 2, uintptr(syscall.Stdout), uintptr(unsafe.Pointer(&info)), 0)
+	_, _, e := syscall.Syscall(procGetConsoleScreenBufferInfo.Addr(), 2, fd, uintptr(unsafe.Pointer(&info)), 0)
 	if e != 0 {
-		return 0, error(e)
+		return 0, 0, error(e)
 	}
-	return int(info.dwSize.X) - 1, nil
+	return int(info.dwSize.X) - 1, int(info.srWindow.Bottom-info.srWindow.Top) + 1, nil
+}
+
+// isTerminal returns true if the handle is a console
+func isTerminal(fd uintptr) bool {
+	var mode uint32
+	r, _, _ := syscall.Syscall(getConsoleMode.Addr(), 2, fd, uintptr(unsafe.Pointer(&mode)), 0)
+	return r != 0
 }
 
 func getCursorPos() (pos coordinates, err error) {
@@ -95,46 +109,25 @@ func setCursorPos(pos coordinates) error {
 	return nil
 }
 
-var ErrPoolWasStarted = errors.New("Bar pool was started")
-
-var echoLocked bool
-var echoLockMutex sync.Mutex
-
-var oldState word
-
-func lockEcho() (quit chan int, err error) {
-	echoLockMutex.Lock()
-	defer echoLockMutex.Unlock()
-	if echoLocked {
-		err = ErrPoolWasStarted
-		return
+// lockEcho disables echo of the console and saves its mode into old
+func lockEcho(fd uintptr, old *termState) (err error) {
+	if _, _, e := syscall.Syscall(getConsoleMode.Addr(), 2, fd, uintptr(unsafe.Pointer(old)), 0); e != 0 {
+		return fmt.Errorf("Can't get terminal settings: %v", e)
 	}
-	echoLocked = true
 
-	if _, _, e := syscall.Syscall(getConsoleMode.Addr(), 2, uintptr(syscall.Stdout), uintptr(unsafe.Pointer(&oldState)), 0); e != 0 {
-		err = fmt.Errorf("Can't get terminal settings: %v", e)
-		return
-	}
-
-	newState := oldState
+	newState := *old
 	const ENABLE_ECHO_INPUT = 0x0004
 	const ENABLE_LINE_INPUT = 0x0002
-	newState = newState & (^(ENABLE_LINE_INPUT | ENABLE_ECHO_INPUT))
-	if _, _, e := syscall.Syscall(setConsoleMode.Addr(), 2, uintptr(syscall.Stdout), uintptr(newState), 0); e != 0 {
-		err = fmt.Errorf("Can't set terminal settings: %v", e)
-		return
+	newState &^= ENABLE_LINE_INPUT | ENABLE_ECHO_INPUT
+	if _, _, e := syscall.Syscall(setConsoleMode.Addr(), 2, fd, uintptr(newState), 0); e != 0 {
+		return fmt.Errorf("Can't set terminal settings: %v", e)
 	}
 	return
 }
 
-func unlockEcho() (err error) {
-	echoLockMutex.Lock()
-	defer echoLockMutex.Unlock()
-	if !echoLocked {
-		return
-	}
-	echoLocked = false
-	if _, _, e := syscall.Syscall(setConsoleMode.Addr(), 2, uintptr(syscall.Stdout), uintptr(oldState), 0); e != 0 {
+// unlockEcho restores the old mode of the console
+func unlockEcho(fd uintptr, old *termState) (err error) {
+	if _, _, e := syscall.Syscall(setConsoleMode.Addr(), 2, fd, uintptr(*old), 0); e != 0 {
 		err = fmt.Errorf("Can't set terminal settings")
 	}
 	return
@@ -4,12 +4,9 @@
 package pb
 
 import (
-	"errors"
 	"fmt"
 	"os"
-	"os/signal"
 	"runtime"
-	"sync"
 	"syscall"
 	"unsafe"
 )
@@ -19,92 +16,65 @@ const (
 	TIOCGWINSZ_OSX = 1074295912
 )
 
-var tty *os.File
+// Signals restoring the terminal state
+var terminateSignals = []os.Signal{os.Interrupt, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGKILL}
 
-var ErrPoolWasStarted = errors.New("Bar pool was started")
+type termState syscall.Termios
 
-var echoLocked bool
-var echoLockMutex sync.Mutex
-
-func init() {
-	var err error
-	tty, err = os.Open("/dev/tty")
+// openTerminal opens /dev/tty, stdin is used if it is not available
+func openTerminal() *os.File {
+	tty, err := os.Open("/dev/tty")
 	if err != nil {
-		tty = os.Stdin
+		return os.Stdin
 	}
+	return tty
 }
 
-// terminalWidth returns width of the terminal.
-func terminalWidth() (int, error) {
+// terminalSize returns width and height of the terminal.
+func terminalSize(fd uintptr) (width, height int, err error) {
 	w := new(window)
 	tio := syscall.TIOCGWINSZ
 	if runtime.GOOS == "darwin" {
 		tio = TIOCGWINSZ_OSX
 	}
-	res, _, err := syscall.Syscall(sysIoctl,
-		tty.Fd(),
+	res, _, e := syscall.Syscall(sysIoctl,
+		fd,
 		uintptr(tio),
 		uintptr(unsafe.Pointer(w)),
 	)
 	if int(res) == -1 {
-		return 0, err
+		return 0, 0, e
 	}
-	return int(w.Col), nil
+	return int(w.Col), int(w.Row), nil
 }
 
-var oldState syscall.Termios
-
-func lockEcho() (quit chan int, err error) {
-	echoLockMutex.Lock()
-	defer echoLockMutex.Unlock()
-	if echoLocked {
-		err = ErrPoolWasStarted
-		return
-	}
-	echoLocked = true
+// isTerminal returns true if the file descriptor is a terminal
+func isTerminal(fd uintptr) bool {
+	var termios syscall.Termios
+	_, _, e := syscall.Syscall6(sysIoctl, fd, ioctlReadTermios, uintptr(unsafe.Pointer(&termios)), 0, 0, 0)
+	return e == 0
+}
 
-	fd := tty.Fd()
-	if _, _, e := syscall.Syscall6(sysIoctl, fd, ioctlReadTermios, uintptr(unsafe.Pointer(&oldState)), 0, 0, 0); e != 0 {
-		err = fmt.Errorf("Can't get terminal settings: %v", e)
-		return
+// lockEcho disables echo of the terminal and saves its state into old
+func lockEcho(fd uintptr, old *termState) (err error) {
+	if _, _, e := syscall.Syscall6(sysIoctl, fd, ioctlReadTermios, uintptr(unsafe.Pointer(old)), 0, 0, 0); e != 0 {
+		return fmt.Errorf("Can't get terminal settings: %v", e)
 	}
 
-	newState := oldState
+	newState := *old
 	newState.Lflag &^= syscall.ECHO
 	newState.Lflag |= syscall.ICANON | syscall.ISIG
 	newState.Iflag |= syscall.ICRNL
 	if _, _, e := syscall.Syscall6(sysIoctl, fd, ioctlWriteTermios, uintptr(unsafe.Pointer(&newState)), 0, 0, 0); e != 0 {
-		err = fmt.Errorf("Can't set terminal settings: %v", e)
-		return
+		return fmt.Errorf("Can't set terminal settings: %v", e)
 	}
-	quit = make(chan int, 1)
-	go catchTerminate(quit)
 	return
 }
 
-func unlockEcho() (err error) {
-	echoLockMutex.Lock()
-	defer echoLockMutex.Unlock()
-	if !echoLocked {
-		return
-	}
-	echoLocked = false
-	fd := tty.Fd()
-	if _, _, e := syscall.Syscall6(sysIoctl, fd, ioctlWriteTermios, uintptr(unsafe.Pointer(&oldState)), 0, 0, 0); e != 0 {
+// unlockEcho restores the old state of the terminal
+func unlockEcho(fd uintptr, old *termState) (err error) {
+	if _, _, e := syscall.Syscall6(sysIoctl, fd, ioctlWriteTermios, uintptr(unsafe.Pointer(old)), 0, 0, 0); e != 0 {
 		err = fmt.Errorf("Can't set terminal settings")
 	}
 	return
 }
-
-// listen exit signals and restore terminal state
-func catchTerminate(quit chan int) {
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGKILL)
-	defer signal.Stop(sig)
-	select {
-	case <-quit:
-		unlockEcho()
-	case <-sig:
-		unlockEcho()
-	}
-}
@@ -3,7 +3,12 @@
 package pb
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"io"
+	"log"
+	"strings"
 	"sync"
 	"time"
 )
@@ -11,22 +16,75 @@ import (
 // Create and start new pool with given bars
 // You need call pool.Stop() after work
 func StartPool(pbs ...*ProgressBar) (pool *Pool, err error) {
-	pool = new(Pool)
-	if err = pool.start(); err != nil {
-		return
-	}
-	pool.Add(pbs...)
+	return StartPoolWithContext(context.Background(), pbs...)
+}
+
+// Create and start new pool with given bars
+// When ctx is done the bars are cancelled and terminal state is restored
+func StartPoolWithContext(ctx context.Context, pbs ...*ProgressBar) (pool *Pool, err error) {
+	pool = NewPool(pbs...)
+	err = pool.StartWithContext(ctx)
 	return
 }
 
+// Create new pool with given bars, it's configured before Start
+// Example:
+// pool := pb.NewPool(first, second)
+// pool.Output = os.Stderr
+// pool.Terminal = pb.NewTerminal(pty)
+// err := pool.Start()
+func NewPool(pbs ...*ProgressBar) *Pool {
+	return &Pool{
+		RefreshRate: DefaultRefreshRate,
+		bars:        pbs,
+		quit:        make(chan int),
+		finished:    make(chan struct{}),
+	}
+}
+
+// Pool prints the bars together
+// The exported fields must be set before Start, use the setters while the pool is running
 type Pool struct {
-	Output        io.Writer
-	RefreshRate   time.Duration
+	Output      io.Writer
+	RefreshRate time.Duration
+	// Remove bars from the pool when they are finished
+	RemoveFinished bool
+	// Print the final line of the bars removed by RemoveFinished above the pool
+	PrintFinished bool
+	// Don't print the children of finished bars
+	CollapseFinished bool
+	// Max number of lines of the pool, terminal height by default
+	// Unfinished bars are shown first, the rest is collapsed into a summary line
+	MaxLines int
+	// Terminal of the pool for the height and echo control,
+	// the terminal of Output or StdTerminal by default
+	Terminal *Terminal
+
+	// the terminal with echo locked by Start
+	echoTerminal *Terminal
+	// terminal of Output, released on stop
+	outputTerminal outputTerminal
+	started        bool
+
 	bars          []*ProgressBar
+	header        *ProgressBar
+	footer        *ProgressBar
+	totals        []*ProgressBar
 	lastBarsCount int
+	// failed bars removed by RemoveFinished
+	removedFailed int
+	lastWidths    []int
+	sizeGen       int
 	quit          chan int
-	m             sync.Mutex
-	finishOnce    sync.Once
+	// closed by the writer after the last print
+	finished chan struct{}
+	done     <-chan struct{}
+	logs     bytes.Buffer
+	stopped  bool
+	// the last print of the stopped pool
+	final      bool
+	m          sync.Mutex
+	finishOnce sync.Once
 }
 
 // Add progress bars.
@@ -34,49 +92,479 @@ func (p *Pool) Add(pbs ...*ProgressBar) {
 	p.m.Lock()
 	defer p.m.Unlock()
 	for _, bar := range pbs {
+		if p.started {
+			p.prepare(bar)
+		}
+		p.bars = append(p.bars, bar)
+	}
+}
+
+// Set refresh rate of the pool
+func (p *Pool) SetRefreshRate(rate time.Duration) *Pool {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.RefreshRate = rate
+	return p
+}
+
+// Set RemoveFinished, remove bars from the pool when they are finished
+func (p *Pool) SetRemoveFinished(remove bool) *Pool {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.RemoveFinished = remove
+	return p
+}
+
+// Set PrintFinished, print the final line of the bars removed by RemoveFinished above the pool
+func (p *Pool) SetPrintFinished(printFinished bool) *Pool {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.PrintFinished = printFinished
+	return p
+}
+
+// Set CollapseFinished, don't print the children of finished bars
+func (p *Pool) SetCollapseFinished(collapse bool) *Pool {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.CollapseFinished = collapse
+	return p
+}
+
+// Set max number of lines of the pool, 0 is the terminal height
+func (p *Pool) SetMaxLines(max int) *Pool {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.MaxLines = max
+	return p
+}
+
+// refreshRate returns RefreshRate
+func (p *Pool) refreshRate() time.Duration {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.RefreshRate
+}
+
+// prepare makes the bar printed by the pool
+// The bars added before start are prepared by start
+func (p *Pool) prepare(bar *ProgressBar) {
+	bar.mu.Lock()
+	pooled := bar.pooled
+	bar.pooled = true
+	if !pooled {
 		bar.ManualUpdate = true
 		bar.NotPrint = true
+		bar.poolOutput = p.Output
+		if bar.Terminal == nil {
+			bar.Terminal = p.Terminal
+		}
+	}
+	bar.mu.Unlock()
+	if !pooled {
 		bar.Start()
-		p.bars = append(p.bars, bar)
 	}
 }
 
-func (p *Pool) start() (err error) {
-	p.RefreshRate = DefaultRefreshRate
-	quit, err := lockEcho()
+// Set the bar pinned above the other bars, nil removes it
+func (p *Pool) SetHeader(bar *ProgressBar) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.header = p.pin(bar)
+}
+
+// Set the bar pinned below the other bars, nil removes it
+func (p *Pool) SetFooter(bar *ProgressBar) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.footer = p.pin(bar)
+}
+
+func (p *Pool) pin(bar *ProgressBar) *ProgressBar {
+	if bar != nil {
+		p.syncTotals()
+		bar.syncGroup()
+		if p.started {
+			p.prepare(bar)
+		}
+	}
+	return bar
+}
+
+// Create new group bar with the combined value, total, speed and time left of all bars in the pool,
+// the bars removed from the pool are counted with their last value and total
+// Use it with SetHeader or SetFooter:
+// pool.SetFooter(pool.NewTotalBar().Prefix("Total "))
+func (p *Pool) NewTotalBar() *ProgressBar {
+	p.m.Lock()
+	defer p.m.Unlock()
+	bar := NewGroupBar(p.bars...)
+	p.totals = append(p.totals, bar)
+	return bar
+}
+
+// syncTotals sets the children of total bars to the bars of the pool
+func (p *Pool) syncTotals() {
+	for _, bar := range p.totals {
+		bar.setGroup(p.bars)
+	}
+}
+
+// removeFromTotals keeps the value and total of the removed bar in the total bars
+func (p *Pool) removeFromTotals(bar *ProgressBar) {
+	for _, total := range p.totals {
+		total.addRemoved(bar)
+	}
+}
+
+// Failed returns the number of the failed bars of the pool, including the ones removed by RemoveFinished
+func (p *Pool) Failed() int {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.failed()
+}
+
+// failed returns the number of the failed bars, must be called with p.m held
+func (p *Pool) failed() int {
+	n := p.removedFailed
+	for _, bar := range p.bars {
+		if bar.IsFailed() {
+			n++
+		}
+	}
+	return n
+}
+
+// Remove progress bar from the pool, it's not printed anymore
+func (p *Pool) Remove(bar *ProgressBar) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	for i, b := range p.bars {
+		if b == bar {
+			p.bars = append(p.bars[:i], p.bars[i+1:]...)
+			p.removeFromTotals(bar)
+			return
+		}
+	}
+}
+
+// Start printing of the pool created by NewPool
+// You need call pool.Stop() after work
+func (p *Pool) Start() error {
+	return p.StartWithContext(context.Background())
+}
+
+// Start printing of the pool created by NewPool
+// When ctx is done the bars are cancelled and terminal state is restored
+func (p *Pool) StartWithContext(ctx context.Context) (err error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.started {
+		return ErrPoolWasStarted
+	}
+	term := p.term()
+	quit, err := term.LockEcho()
 	if err != nil {
+		p.outputTerminal.release()
 		return
 	}
-	p.quit = make(chan int)
+	p.echoTerminal, p.done = term, ctx.Done()
+	p.init()
 	go p.writer(quit)
 	return
 }
 
+// init prepares the bars added before start, from now the bars are prepared when they are added
+// Must be called with p.m held
+func (p *Pool) init() {
+	p.started = true
+	if p.RefreshRate <= 0 {
+		p.RefreshRate = DefaultRefreshRate
+	}
+	for _, bar := range append(p.bars, p.header, p.footer) {
+		if bar != nil {
+			p.prepare(bar)
+		}
+	}
+}
+
+// Write implements io.Writer, the text is printed above the bars on the next refresh
+// Incomplete lines are held until a newline arrives or the pool is stopped
+func (p *Pool) Write(b []byte) (n int, err error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.stopped {
+		p.output(string(b))
+		return len(b), nil
+	}
+	return p.logs.Write(b)
+}
+
+// Create new logger printing above the bars
+// See log.New for prefix and flag
+func (p *Pool) NewLogger(prefix string, flag int) *log.Logger {
+	return log.New(p, prefix, flag)
+}
+
+// writer prints the pool until it's stopped or cancelled,
+// bars can be added after all the previous ones are finished
 func (p *Pool) writer(finish chan int) {
+	defer close(p.finished)
+	defer p.stop()
 	var first = true
 	for {
 		select {
-		case <-time.After(p.RefreshRate):
-			if p.print(first) {
-				p.print(false)
-				finish <- 1
-				return
-			}
+		case <-time.After(p.refreshRate()):
+			p.print(first)
 			first = false
 		case <-p.quit:
+			// print the last state and logs, the pinned bars are finished with the bars
+			p.m.Lock()
+			p.final = true
+			p.m.Unlock()
+			p.print(first)
+			finish <- 1
+			return
+		case <-p.done:
+			p.cancel(first)
 			finish <- 1
 			return
 		}
 	}
 }
 
+// stop marks the pool as stopped, from now the logs are printed immediately
+// and the terminal of Output is released
+func (p *Pool) stop() {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.stopped = true
+	p.outputTerminal.release()
+	if p.logs.Len() > 0 {
+		p.output(p.logs.String() + "\n")
+		p.logs.Reset()
+	}
+}
+
+// render updates the bars, removes the finished ones when RemoveFinished is set
+// and returns the lines printed above the pool (final lines of the removed bars and the logs)
+// and the lines of the bars in the pool
+// Must be called with p.m held
+func (p *Pool) render() (above, lines []string, isFinished bool) {
+	isFinished = true
+	bars := p.bars[:0]
+	var rows []poolRow
+	for _, bar := range p.bars {
+		finished := bar.IsFinished()
+		if finished && p.RemoveFinished {
+			if bar.IsFailed() {
+				p.removedFailed++
+			}
+			bar.Update()
+			p.removeFromTotals(bar)
+			if p.PrintFinished {
+				above = append(above, bar.String())
+			}
+			continue
+		}
+		if !finished {
+			isFinished = false
+		}
+		bars = append(bars, bar)
+		rows = p.appendRows(rows, bar, 0)
+	}
+	p.bars = bars
+	// the pool without bars is waiting for new ones
+	isFinished = isFinished && len(bars) > 0
+
+	// pinned bars
+	p.syncTotals()
+	if failed := p.failed(); failed > 0 {
+		for _, bar := range p.totals {
+			bar.SetMessage(fmt.Sprintf("%d failed", failed))
+		}
+	}
+	var header, footer []string
+	for _, bar := range []*ProgressBar{p.header, p.footer} {
+		if bar == nil {
+			continue
+		}
+		if isFinished && p.final {
+			bar.Finish()
+		}
+		bar.Update()
+		if bar == p.header {
+			header = append(header, bar.String())
+		} else {
+			footer = append(footer, bar.String())
+		}
+	}
+	lines = append(header, p.limitLines(rows, len(header)+len(footer))...)
+	lines = append(lines, footer...)
+
+	// complete lines of the logs
+	if data := p.logs.Bytes(); bytes.IndexByte(data, '\n') >= 0 {
+		i := bytes.LastIndexByte(data, '\n')
+		for _, line := range strings.Split(string(data[:i]), "\n") {
+			above = append(above, p.eraseLine(strings.TrimSuffix(line, "\r")))
+		}
+		p.logs.Next(i + 1)
+	}
+	return
+}
+
+// poolRow is a printed line of the bar in the pool
+type poolRow struct {
+	line         string
+	done, failed bool
+}
+
+// appendRows updates the bar and adds the rows of the bar and its children indented by depth
+func (p *Pool) appendRows(rows []poolRow, bar *ProgressBar, depth int) []poolRow {
+	indent := strings.Repeat("  ", depth)
+	bar.mu.Lock()
+	bar.indent = len(indent)
+	bar.mu.Unlock()
+	bar.Update()
+	finished := bar.IsFinished()
+	rows = append(rows, poolRow{indent + bar.String(), finished, bar.IsFailed()})
+	if finished && p.CollapseFinished {
+		return rows
+	}
+	for _, child := range bar.Children() {
+		p.prepare(child)
+		rows = p.appendRows(rows, child, depth+1)
+	}
+	return rows
+}
+
+// limitLines returns the lines of the rows fitting into MaxLines or the terminal height
+// without reserved lines. Unfinished bars go first, then the failed ones,
+// the rest is collapsed into a summary line
+func (p *Pool) limitLines(rows []poolRow, reserved int) (lines []string) {
+	max := p.MaxLines
+	if max <= 0 {
+		// keep the last line of the terminal for the cursor
+		if height, err := p.term().Height(); err == nil {
+			max = height - 1
+		}
+	}
+	if max > 0 {
+		// at least the summary line is shown
+		if max -= reserved; max < 1 {
+			max = 1
+		}
+	}
+	if max <= 0 || len(rows) <= max {
+		for _, row := range rows {
+			lines = append(lines, row.line)
+		}
+		return
+	}
+	show := make([]bool, len(rows))
+	n := max - 1
+	for _, group := range []struct{ done, failed bool }{{false, false}, {true, true}, {true, false}} {
+		for i, row := range rows {
+			if n > 0 && row.done == group.done && row.failed == group.failed {
+				show[i] = true
+				n--
+			}
+		}
+	}
+	var hidden, hiddenFinished, hiddenFailed int
+	for i, row := range rows {
+		if show[i] {
+			lines = append(lines, row.line)
+			continue
+		}
+		hidden++
+		if row.done {
+			hiddenFinished++
+		}
+		if row.failed {
+			hiddenFailed++
+		}
+	}
+	summary := fmt.Sprintf("+%d more (%d finished)", hidden, hiddenFinished)
+	if hiddenFailed > 0 {
+		summary = fmt.Sprintf("+%d more (%d finished, %d failed)", hidden, hiddenFinished, hiddenFailed)
+	}
+	return append(lines, p.eraseLine(summary))
+}
+
+// resized returns the number of terminal rows of the last printed lines
+// if the terminal was resized after they were printed
+func (p *Pool) resized() (rows int, ok bool) {
+	termWidth, _, gen, err := p.term().size()
+	if gen == p.sizeGen || err != nil {
+		return 0, false
+	}
+	p.sizeGen = gen
+	return wrappedRows(p.lastWidths, termWidth), true
+}
+
+// setLastWidths saves the widths of the printed lines of the pool
+func (p *Pool) setLastWidths(lines []string) {
+	p.lastWidths = p.lastWidths[:0]
+	for _, line := range lines {
+		p.lastWidths = append(p.lastWidths, escapeAwareRuneCountInString(line))
+	}
+}
+
+// term returns Terminal, the terminal of Output if it is a terminal file or StdTerminal
+// Must be called with p.m held
+func (p *Pool) term() *Terminal {
+	if p.Terminal != nil {
+		return p.Terminal
+	}
+	return p.outputTerminal.get(p.Output)
+}
+
+func (p *Pool) output(out string) {
+	if p.Output != nil {
+		fmt.Fprint(p.Output, out)
+	} else {
+		fmt.Print(out)
+	}
+}
+
+// cancel cancels all bars, prints their final state and restores terminal state
+func (p *Pool) cancel(first bool) {
+	p.m.Lock()
+	bars := append([]*ProgressBar(nil), p.bars...)
+	p.m.Unlock()
+	for _, bar := range bars {
+		bar.cancel()
+	}
+	p.print(first)
+	p.unlockEcho()
+}
+
 // Restore terminal state and close pool
 func (p *Pool) Stop() error {
 	// Wait until one final refresh has passed.
-	time.Sleep(p.RefreshRate)
+	time.Sleep(p.refreshRate())
 
 	p.finishOnce.Do(func() {
 		close(p.quit)
 	})
-	return unlockEcho()
+	p.m.Lock()
+	started := p.started
+	p.m.Unlock()
+	if started {
+		// the last print and the logs go before the echo is restored
+		<-p.finished
+	}
+	return p.unlockEcho()
+}
+
+// unlockEcho restores echo of the terminal locked by start
+func (p *Pool) unlockEcho() error {
+	p.m.Lock()
+	term := p.echoTerminal
+	p.m.Unlock()
+	if term == nil {
+		return nil
+	}
+	return term.UnlockEcho()
 }
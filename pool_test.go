@@ -0,0 +1,311 @@
+// +build linux darwin freebsd netbsd openbsd solaris dragonfly
+
+package pb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestPool(pbs ...*ProgressBar) (*Pool, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	pool := NewPool(pbs...)
+	pool.Output = buf
+	for _, bar := range pbs {
+		bar.SetWidth(20)
+	}
+	// started pool without the writer
+	pool.m.Lock()
+	pool.init()
+	pool.m.Unlock()
+	return pool, buf
+}
+
+func Test_PoolRemove(t *testing.T) {
+	first, second, third := New(10).Prefix("1"), New(10).Prefix("2"), New(10).Prefix("3")
+	pool, buf := newTestPool(first, second, third)
+	pool.print(true)
+	if lines := strings.Count(buf.String(), "\n"); lines != 3 {
+		t.Fatalf("Expected 3 lines, got %q", buf.String())
+	}
+
+	buf.Reset()
+	pool.Remove(second)
+	pool.print(false)
+	out := buf.String()
+	if !strings.HasPrefix(out, "\033[3A") || !strings.HasSuffix(out, "\033[J") {
+		t.Errorf("Expected cursor up 3 lines and clear below, got %q", out)
+	}
+	if strings.Count(out, "\n") != 2 || strings.Contains(out, "\r2") {
+		t.Errorf("Expected lines of first and third bars, got %q", out)
+	}
+	if pool.lastBarsCount != 2 {
+		t.Errorf("Expected 2 bars, got %d", pool.lastBarsCount)
+	}
+}
+
+func Test_PoolRemoveFinished(t *testing.T) {
+	first, second := New(10).Prefix("1"), New(10).Prefix("2")
+	pool, buf := newTestPool(first, second)
+	pool.RemoveFinished = true
+	pool.PrintFinished = true
+	pool.print(true)
+
+	buf.Reset()
+	first.Finish()
+	if pool.print(false) {
+		t.Error("Pool must not be finished")
+	}
+	out := buf.String()
+	// final line of the first bar goes above the pool, the pool has the same height
+	if !strings.HasPrefix(out, "\033[2A\r1") || strings.Count(out, "\n") != 2 || strings.Contains(out, "\033[J") {
+		t.Errorf("Unexpected output %q", out)
+	}
+	if pool.lastBarsCount != 1 {
+		t.Errorf("Expected 1 bar, got %d", pool.lastBarsCount)
+	}
+
+	// the empty pool waits for new bars
+	buf.Reset()
+	second.Finish()
+	pool.PrintFinished = false
+	if pool.print(false) {
+		t.Error("Empty pool must not be finished")
+	}
+	if out := buf.String(); out != "\033[1A\033[J" {
+		t.Errorf("Unexpected output %q", out)
+	}
+	if pool.lastBarsCount != 0 {
+		t.Errorf("Expected 0 bars, got %d", pool.lastBarsCount)
+	}
+}
+
+func Test_PoolAddAfterFinished(t *testing.T) {
+	first, second := New(10).Prefix("1"), New(10).Prefix("2")
+	pool, buf := newTestPool(first)
+	pool.RefreshRate = 10 * time.Millisecond
+	finish := make(chan int, 1)
+	go pool.writer(finish)
+
+	first.Finish()
+	time.Sleep(50 * time.Millisecond)
+	// the pool keeps running after all bars are finished
+	pool.Add(second.SetWidth(20))
+	time.Sleep(50 * time.Millisecond)
+	close(pool.quit)
+	<-finish
+	if out := buf.String(); !strings.Contains(out, "\r2") {
+		t.Errorf("Expected the bar added after the first is finished, got %q", out)
+	}
+}
+
+func Test_PoolStopWaitsWriter(t *testing.T) {
+	pool, buf := newTestPool(New(10).Prefix("1"))
+	pool.RefreshRate = 10 * time.Millisecond
+	go pool.writer(make(chan int, 1))
+	// incomplete line is printed by the stopped writer
+	pool.Write([]byte("tail"))
+	pool.Stop()
+	out := buf.String()
+	if !strings.HasSuffix(out, "tail\n") {
+		t.Errorf("Expected the logs printed before Stop returns, got %q", out)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if buf.String() != out {
+		t.Errorf("Unexpected print after Stop %q", buf.String()[len(out):])
+	}
+}
+
+func Test_PoolColor(t *testing.T) {
+	// the pooled bar is printed to the output of the pool, not to stdout
+	bar := New(10).SetStyle("fill", FgGreen)
+	_, buf := newTestPool(bar)
+	if bar.printer() != buf || bar.isColor() {
+		t.Error("Expected no colors for the pool printing to a buffer")
+	}
+}
+
+func Test_PoolTerminal(t *testing.T) {
+	f, err := ioutil.TempFile("", "pb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// the terminal is set before start, its echo is locked
+	pool := NewPool(New(10))
+	pool.Terminal = NewTerminal(f)
+	if err := pool.Start(); err == nil {
+		t.Error("Expected error of the echo lock of the file")
+	}
+
+	// echo of the locked terminal is restored, not of the current one
+	locked := NewTerminal(f)
+	locked.echoLocked = true
+	pool.echoTerminal = locked
+	pool.Terminal = StdTerminal()
+	pool.RefreshRate = time.Millisecond
+	pool.Stop()
+	if locked.echoLocked {
+		t.Error("Expected echo of the locked terminal restored")
+	}
+}
+
+func Test_PoolWrite(t *testing.T) {
+	bar := New(10).Prefix("1")
+	pool, buf := newTestPool(bar)
+	logger := pool.NewLogger("log: ", 0)
+	pool.print(true)
+
+	buf.Reset()
+	logger.Println("first")
+	pool.Write([]byte("second\nthi"))
+	pool.print(false)
+	out := buf.String()
+	expected := "\033[1A\rlog: first\033[K\n\rsecond\033[K\n\r1"
+	if !strings.HasPrefix(out, expected) || strings.Count(out, "\n") != 3 {
+		t.Errorf("Expected %q, got %q", expected, out)
+	}
+
+	// incomplete line is held
+	buf.Reset()
+	pool.print(false)
+	if out := buf.String(); strings.Contains(out, "thi") {
+		t.Errorf("Unexpected incomplete line in %q", out)
+	}
+	buf.Reset()
+	pool.Write([]byte("rd\n"))
+	pool.print(false)
+	if out := buf.String(); !strings.Contains(out, "\rthird\033[K\n") {
+		t.Errorf("Expected third line in %q", out)
+	}
+
+	// stopped pool prints immediately
+	pool.stop()
+	buf.Reset()
+	pool.Write([]byte("after\n"))
+	if out := buf.String(); out != "after\n" {
+		t.Errorf("Expected %q, got %q", "after\n", out)
+	}
+}
+
+func Test_PoolMaxLines(t *testing.T) {
+	var bars []*ProgressBar
+	for i := 0; i < 6; i++ {
+		bars = append(bars, New(10).Prefix(string(rune('a'+i))))
+	}
+	pool, buf := newTestPool(bars...)
+	pool.MaxLines = 3
+	bars[0].Finish()
+	bars[2].Finish()
+	pool.print(true)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %q", buf.String())
+	}
+	// unfinished bars first
+	if !strings.HasPrefix(lines[0], "\rb") || !strings.HasPrefix(lines[1], "\rd") {
+		t.Errorf("Expected bars b and d, got %q", lines)
+	}
+	if expected := "\r+4 more (2 finished)\033[K"; lines[2] != expected {
+		t.Errorf("Expected summary %q, got %q", expected, lines[2])
+	}
+}
+
+func Test_PoolFailed(t *testing.T) {
+	var bars []*ProgressBar
+	for i := 0; i < 5; i++ {
+		bars = append(bars, New(10).Prefix(string(rune('a'+i))))
+	}
+	pool, buf := newTestPool(bars...)
+	total := pool.NewTotalBar().SetWidth(40)
+	pool.SetFooter(total)
+	pool.MaxLines = 5
+	bars[0].Finish()
+	bars[1].Fail(nil)
+	bars[2].Fail(nil)
+	pool.print(true)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 lines, got %q", lines)
+	}
+	// unfinished bars, then failed ones
+	if !strings.HasPrefix(lines[0], "\rb") || !strings.HasPrefix(lines[1], "\rd") || !strings.HasPrefix(lines[2], "\re") {
+		t.Errorf("Expected bars b, d and e, got %q", lines)
+	}
+	if expected := "\r+2 more (2 finished, 1 failed)\033[K"; lines[3] != expected {
+		t.Errorf("Expected summary %q, got %q", expected, lines[3])
+	}
+	if !strings.Contains(lines[4], "2 failed") || pool.Failed() != 2 {
+		t.Errorf("Expected 2 failed bars in the total %q", lines[4])
+	}
+}
+
+func Test_PoolTotalBar(t *testing.T) {
+	first, second := New(10).Prefix("1"), New(30).Prefix("2")
+	pool, buf := newTestPool(first, second)
+	total := pool.NewTotalBar().Prefix("T").SetWidth(20)
+	pool.SetFooter(total)
+	pool.SetHeader(NewGroupBar(first).Prefix("H").SetWidth(20))
+
+	// bars added later are counted too
+	third := New(60).Prefix("3")
+	pool.Add(third.SetWidth(20))
+	first.Add(10)
+	third.Add(30)
+	pool.print(true)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "\rH") || !strings.HasPrefix(lines[4], "\rT") {
+		t.Fatalf("Expected header, 3 bars and footer, got %q", lines)
+	}
+	if total.Get() != 40 || total.Total != 100 {
+		t.Errorf("Expected total 40 / 100, got %d / %d", total.Get(), total.Total)
+	}
+
+	// removed bars are kept in the total
+	pool.RemoveFinished = true
+	first.Finish()
+	pool.Remove(second)
+	third.Add(5)
+	pool.print(false)
+	if total.Get() != 45 || total.Total != 100 {
+		t.Errorf("Expected total 45 / 100, got %d / %d", total.Get(), total.Total)
+	}
+}
+
+func Test_PoolChildBars(t *testing.T) {
+	first, second := New(10).Prefix("a"), New(10).Prefix("b")
+	parent := New(100).Prefix("p").AddChild(first, 1)
+	pool, buf := newTestPool(parent)
+	// children added later are printed too
+	parent.AddChild(second.SetWidth(20), 1)
+	first.SetWidth(20)
+	pool.CollapseFinished = true
+	pool.print(true)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "\rp") ||
+		!strings.HasPrefix(lines[1], "\r  a") || !strings.HasPrefix(lines[2], "\r  b") {
+		t.Fatalf("Expected parent and indented children, got %q", lines)
+	}
+	if len(lines[1]) != len(lines[0]) {
+		t.Errorf("Expected the same width of lines, got %q", lines)
+	}
+
+	// finished subtree is collapsed
+	buf.Reset()
+	first.Finish()
+	second.Finish()
+	pool.print(false)
+	if out := buf.String(); strings.Count(out, "\n") != 1 || !strings.Contains(out, "\033[J") {
+		t.Errorf("Expected collapsed parent, got %q", out)
+	}
+}
@@ -5,6 +5,7 @@ package pb
 import (
 	"fmt"
 	"log"
+	"strings"
 )
 
 func (p *Pool) print(first bool) bool {
@@ -12,34 +13,49 @@ func (p *Pool) print(first bool) bool {
 	defer p.m.Unlock()
 	var out string
 	if !first {
-		coords, err := getCursorPos()
-		if err != nil {
-			log.Panic(err)
-		}
-		coords.Y -= int16(p.lastBarsCount)
-		if coords.Y < 0 {
-			coords.Y = 0
-		}
-		coords.X = 0
-
-		err = setCursorPos(coords)
-		if err != nil {
-			log.Panic(err)
-		}
-	}
-	isFinished := true
-	for _, bar := range p.bars {
-		if !bar.IsFinished() {
-			isFinished = false
-		}
-		bar.Update()
-		out += fmt.Sprintf("\r%s\n", bar.String())
-	}
-	if p.Output != nil {
-		fmt.Fprint(p.Output, out)
-	} else {
-		fmt.Print(out)
-	}
-	p.lastBarsCount = len(p.bars)
+		moveCursorUp(p.lastBarsCount)
+	}
+	above, lines, isFinished := p.render()
+	for _, line := range append(above, lines...) {
+		out += fmt.Sprintf("\r%s\n", line)
+	}
+	// clear the lines left from the bigger pool
+	stale := p.lastBarsCount - len(above) - len(lines)
+	if stale > 0 {
+		width, _ := p.term().Width()
+		out += strings.Repeat(strings.Repeat(" ", width)+"\n", stale)
+	}
+	p.output(out)
+	if stale > 0 {
+		moveCursorUp(stale)
+	}
+	p.lastBarsCount = len(lines)
 	return isFinished
 }
+
+// moveCursorUp moves cursor n lines up to the first column
+func moveCursorUp(n int) {
+	coords, err := getCursorPos()
+	if err != nil {
+		log.Panic(err)
+	}
+	coords.Y -= int16(n)
+	if coords.Y < 0 {
+		coords.Y = 0
+	}
+	coords.X = 0
+
+	err = setCursorPos(coords)
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// eraseLine returns the line padded with spaces to the terminal width
+func (p *Pool) eraseLine(line string) string {
+	width, _ := p.term().Width()
+	if n := width - escapeAwareRuneCountInString(line); n > 0 {
+		line += strings.Repeat(" ", n)
+	}
+	return line
+}
@@ -8,22 +8,27 @@ func (p *Pool) print(first bool) bool {
 	p.m.Lock()
 	defer p.m.Unlock()
 	var out string
-	if !first {
+	if rows, resized := p.resized(); !first && resized {
+		// the lines could be wrapped, the whole pool is redrawn
+		out = clearRows(rows)
+	} else if !first && p.lastBarsCount > 0 {
 		out = fmt.Sprintf("\033[%dA", p.lastBarsCount)
 	}
-	isFinished := true
-	for _, bar := range p.bars {
-		if !bar.IsFinished() {
-			isFinished = false
-		}
-		bar.Update()
-		out += fmt.Sprintf("\r%s\n", bar.String())
+	above, lines, isFinished := p.render()
+	for _, line := range append(above, lines...) {
+		out += fmt.Sprintf("\r%s\n", line)
 	}
-	if p.Output != nil {
-		fmt.Fprint(p.Output, out)
-	} else {
-		fmt.Print(out)
+	p.setLastWidths(lines)
+	// clear the lines left from the bigger pool
+	if len(above)+len(lines) < p.lastBarsCount {
+		out += "\033[J"
 	}
-	p.lastBarsCount = len(p.bars)
+	p.output(out)
+	p.lastBarsCount = len(lines)
 	return isFinished
 }
+
+// eraseLine returns the line which clears the rest of the terminal line
+func (p *Pool) eraseLine(line string) string {
+	return line + "\033[K"
+}
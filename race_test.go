@@ -0,0 +1,165 @@
+// +build linux darwin freebsd netbsd openbsd solaris dragonfly
+
+package pb
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Stress tests for the concurrent use of the bars, run them with -race
+
+// parallel runs the functions n times each in their own goroutines and waits for them
+func parallel(n int, fns ...func(i int)) {
+	var wg sync.WaitGroup
+	for _, f := range fns {
+		wg.Add(1)
+		go func(f func(i int)) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				f(i)
+			}
+		}(f)
+	}
+	wg.Wait()
+}
+
+func Test_RaceBar(t *testing.T) {
+	bar := New(1000).SetRefreshRate(time.Millisecond).SetMode(MODE_TERMINAL)
+	bar.Output = &syncBuffer{}
+	bar.Start()
+	parallel(500,
+		func(i int) { bar.Increment() },
+		func(i int) { bar.Add64(1) },
+		func(i int) { bar.AddTotal64(1); bar.SetTotal64(bar.total()) },
+		func(i int) { bar.Prefix(fmt.Sprint(i)).Postfix(fmt.Sprint(i)) },
+		func(i int) { bar.SetMessage(fmt.Sprint("file ", i)) },
+		func(i int) { bar.SetWidth(40 + i%20).SetMaxWidth(80) },
+		func(i int) { bar.SetUnits(U_BYTES).SetRefreshRate(time.Millisecond) },
+		func(i int) { bar.Format("[=>-]").SetTheme(ThemeBlocks) },
+		func(i int) { bar.SetTemplate("{{prefix}} {{bar}} {{percent}}") },
+		func(i int) { bar.SetStyle("fill", FgGreen).SetSpinner(SpinnerDots) },
+		func(i int) { bar.SetThresholds(Threshold{50, Style{FgRed}}).SetGradient(RGB{}, RGB{}) },
+		func(i int) { _, _ = bar.String(), bar.Get() },
+		func(i int) { bar.IsFinished() },
+		func(i int) { bar.Pause().Resume().IsPaused() },
+		func(i int) { bar.Update() },
+		func(i int) { bar.Write(make([]byte, 1)) },
+	)
+	parallel(2, func(i int) { bar.Finish() }, func(i int) { bar.Finish() })
+}
+
+func Test_RaceBarFinish(t *testing.T) {
+	out := &syncBuffer{}
+	bar := New(100).SetRefreshRate(time.Millisecond).SetMode(MODE_TERMINAL)
+	bar.ShowTimeLeft, bar.ShowFinalTime = false, true
+	bar.Output = out
+	bar.Start()
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				bar.Increment()
+			}
+		}
+	}()
+	time.Sleep(5 * time.Millisecond)
+	bar.Finish()
+	close(stop)
+	<-done
+	// the final line with the total time is the last print
+	s := out.String()
+	if !strings.HasSuffix(s, "\n") {
+		t.Fatalf("Output must end with the newline: %q", s)
+	}
+	if last := s[strings.LastIndex(s, "\r")+1:]; !strings.Contains(last, " 0s") {
+		t.Errorf("The last print must be the final line: %q", last)
+	}
+}
+
+func Test_RaceBarStart(t *testing.T) {
+	bar := New(100).SetRefreshRate(time.Millisecond)
+	bar.NotPrint = true
+	parallel(1,
+		func(i int) { bar.Start() },
+		func(i int) { bar.Add(50); bar.Update() },
+		func(i int) { bar.Set(10); _ = bar.String() },
+	)
+	bar.Finish()
+}
+
+func Test_RaceGroupAndChildren(t *testing.T) {
+	first, second := New(100), New(100)
+	group := NewGroupBar(first, second)
+	parent := New(100).AddChild(first, 1)
+	for _, bar := range []*ProgressBar{first, second, group, parent} {
+		bar.NotPrint = true
+		bar.SetRefreshRate(time.Millisecond).Start()
+	}
+	parallel(100,
+		func(i int) { first.Increment() },
+		func(i int) { second.Increment() },
+		func(i int) { parent.AddChild(New(10), 1); parent.Children() },
+		func(i int) { group.Update(); parent.Update() },
+	)
+	for _, bar := range []*ProgressBar{first, second, group, parent} {
+		bar.Finish()
+	}
+}
+
+func Test_RacePool(t *testing.T) {
+	pool := NewPool()
+	pool.Output, pool.RefreshRate = &syncBuffer{}, time.Millisecond
+	pool.m.Lock()
+	pool.init()
+	pool.m.Unlock()
+	finish := make(chan int, 1)
+	done := make(chan struct{})
+	go func() {
+		pool.writer(finish)
+		close(done)
+	}()
+
+	var bars []*ProgressBar
+	for i := 0; i < 10; i++ {
+		bars = append(bars, New(100).Prefix(fmt.Sprint(i)))
+	}
+	parallel(100,
+		func(i int) { pool.Add(bars[i%len(bars)]) },
+		func(i int) { bars[i%len(bars)].Increment() },
+		func(i int) { pool.Remove(bars[(i+5)%len(bars)]) },
+		func(i int) { fmt.Fprintln(pool, "log", i) },
+		func(i int) { pool.SetHeader(New(10)); pool.SetFooter(pool.NewTotalBar()) },
+		func(i int) { pool.SetMaxLines(i % 5).SetRefreshRate(time.Millisecond) },
+		func(i int) { pool.SetRemoveFinished(i%2 == 0).SetPrintFinished(true).SetCollapseFinished(true) },
+		func(i int) { pool.Failed() },
+	)
+	pool.finishOnce.Do(func() { close(pool.quit) })
+	<-done
+	fmt.Fprintln(pool, "after")
+	for _, bar := range bars {
+		bar.Finish()
+	}
+}
+
+func Test_RacePoolFields(t *testing.T) {
+	bar := New(10)
+	pool := NewPool(bar)
+	pool.Output = &bytes.Buffer{}
+	pool.m.Lock()
+	pool.init()
+	pool.m.Unlock()
+	parallel(100,
+		func(i int) { pool.print(i == 0) },
+		func(i int) { bar.Increment() },
+	)
+}
@@ -2,14 +2,33 @@ package pb
 
 import (
 	"io"
+	"sync/atomic"
 )
 
 // It's proxy reader, implement io.Reader
 type Reader struct {
+	readAtN int64 // bytes read by ReadAt, readAtN must be first member of struct for atomic access on 32-bit
 	io.Reader
 	bar *ProgressBar
 }
 
+// wrapReader returns the proxy reader with io.Seeker and io.ReaderAt of r,
+// only when r implements them
+func wrapReader(r io.Reader, bar *ProgressBar) io.ReadCloser {
+	pr := &Reader{Reader: r, bar: bar}
+	_, isSeeker := r.(io.Seeker)
+	_, isReaderAt := r.(io.ReaderAt)
+	switch {
+	case isSeeker && isReaderAt:
+		return seekReaderAt{pr}
+	case isSeeker:
+		return seekReader{pr}
+	case isReaderAt:
+		return readerAt{pr}
+	}
+	return pr
+}
+
 func (r *Reader) Read(p []byte) (n int, err error) {
 	n, err = r.Reader.Read(p)
 	r.bar.Add(n)
@@ -23,3 +42,68 @@ func (r *Reader) Close() (err error) {
 	}
 	return
 }
+
+// WriteTo uses io.WriterTo of the underlying reader when it implements one
+func (r *Reader) WriteTo(w io.Writer) (n int64, err error) {
+	wt, ok := r.Reader.(io.WriterTo)
+	if !ok {
+		// hide WriteTo to avoid recursion in io.Copy
+		return io.Copy(w, struct{ io.Reader }{r})
+	}
+	cw := &countingWriter{Writer: w, bar: r.bar}
+	n, err = wt.WriteTo(cw)
+	r.bar.Add64(n - cw.n)
+	return
+}
+
+// seek seeks the underlying io.Seeker and sets the bar to the new offset with the bytes of ReadAt,
+// so a resumed read starts from the offset instead of counting the skipped bytes
+// The seek relative to the end (like the size check of http.ServeContent) is not a progress,
+// the bar is not moved until the next seek or read
+func (r *Reader) seek(offset int64, whence int) (pos int64, err error) {
+	if pos, err = r.Reader.(io.Seeker).Seek(offset, whence); err != nil {
+		return
+	}
+	if whence != io.SeekEnd {
+		r.bar.Set64(pos + atomic.LoadInt64(&r.readAtN))
+	}
+	return
+}
+
+// readAt reads from the underlying io.ReaderAt, read bytes are added to the bar,
+// the sequential offset is not moved
+func (r *Reader) readAt(p []byte, off int64) (n int, err error) {
+	n, err = r.Reader.(io.ReaderAt).ReadAt(p, off)
+	atomic.AddInt64(&r.readAtN, int64(n))
+	r.bar.Add(n)
+	return
+}
+
+// proxy readers of io.Seeker and io.ReaderAt
+type (
+	seekReader   struct{ *Reader }
+	readerAt     struct{ *Reader }
+	seekReaderAt struct{ *Reader }
+)
+
+func (r seekReader) Seek(offset int64, whence int) (int64, error) { return r.seek(offset, whence) }
+
+func (r readerAt) ReadAt(p []byte, off int64) (int, error) { return r.readAt(p, off) }
+
+func (r seekReaderAt) Seek(offset int64, whence int) (int64, error) { return r.seek(offset, whence) }
+
+func (r seekReaderAt) ReadAt(p []byte, off int64) (int, error) { return r.readAt(p, off) }
+
+// countingWriter adds written bytes to the bar and remembers their number
+type countingWriter struct {
+	io.Writer
+	bar *ProgressBar
+	n   int64
+}
+
+func (w *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = w.Writer.Write(p)
+	w.n += int64(n)
+	w.bar.Add(n)
+	return
+}
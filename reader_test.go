@@ -0,0 +1,120 @@
+package pb
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func Test_ProxyReaderSeek(t *testing.T) {
+	data := strings.Repeat("0123456789", 10)
+	bar := New(len(data))
+	r := bar.WrapReader(strings.NewReader(data)).(io.Seeker)
+
+	// resume from the middle
+	if pos, err := r.Seek(50, io.SeekStart); pos != 50 || err != nil {
+		t.Fatalf("Expected 50, nil; got %d, %v", pos, err)
+	}
+	if bar.Get() != 50 {
+		t.Errorf("Expected bar value 50, got %d", bar.Get())
+	}
+	if _, err := io.CopyN(ioutil.Discard, r.(io.Reader), 10); err != nil {
+		t.Fatal(err)
+	}
+	if pos, _ := r.Seek(-20, io.SeekCurrent); pos != 40 || bar.Get() != 40 {
+		t.Errorf("Expected 40, got position %d, bar value %d", pos, bar.Get())
+	}
+}
+
+func Test_ProxyReaderSeekResumed(t *testing.T) {
+	// the reader is already at the offset and the bar is set for the resume
+	sr := strings.NewReader(strings.Repeat("0123456789", 10))
+	sr.Seek(30, io.SeekStart)
+	bar := New(100)
+	bar.Set(30)
+	r := bar.WrapReader(sr)
+	if pos, _ := r.(io.Seeker).Seek(40, io.SeekStart); pos != 40 || bar.Get() != 40 {
+		t.Errorf("Expected 40, got position %d, bar value %d", pos, bar.Get())
+	}
+}
+
+func Test_ProxyReaderReadAt(t *testing.T) {
+	bar := New(100)
+	r := bar.WrapReader(strings.NewReader("0123456789"))
+	p := make([]byte, 3)
+	if n, err := r.(io.ReaderAt).ReadAt(p, 5); n != 3 || err != nil || string(p) != "567" {
+		t.Fatalf("Unexpected ReadAt result: %d, %v, %q", n, err, p)
+	}
+	if bar.Get() != 3 {
+		t.Errorf("Expected bar value 3, got %d", bar.Get())
+	}
+	// sequential offset is not moved
+	if n, _ := r.Read(p); n != 3 || string(p) != "012" {
+		t.Errorf("Unexpected Read result: %d, %q", n, p)
+	}
+	// seek keeps the bytes of ReadAt
+	if pos, _ := r.(io.Seeker).Seek(5, io.SeekStart); pos != 5 || bar.Get() != 8 {
+		t.Errorf("Expected 5, got position %d, bar value %d", pos, bar.Get())
+	}
+}
+
+func Test_ProxyReaderSeekEnd(t *testing.T) {
+	bar := New(10)
+	r := bar.WrapReader(strings.NewReader("0123456789")).(io.ReadSeeker)
+	// the size check of http.ServeContent is not a progress
+	if size, _ := r.Seek(0, io.SeekEnd); size != 10 || bar.Get() != 0 {
+		t.Errorf("Expected size 10, bar value 0; got %d, %d", size, bar.Get())
+	}
+	r.Seek(0, io.SeekStart)
+	if n, err := io.Copy(ioutil.Discard, r); n != 10 || err != nil || bar.Get() != 10 {
+		t.Errorf("Unexpected copy result: %d, %v, bar %d", n, err, bar.Get())
+	}
+}
+
+func Test_ProxyReaderWriteTo(t *testing.T) {
+	data := strings.Repeat("data", 1000)
+	bar := New(len(data))
+	buf := &bytes.Buffer{}
+	// strings.Reader implements io.WriterTo
+	n, err := io.Copy(buf, bar.NewProxyReader(strings.NewReader(data)))
+	if err != nil || n != int64(len(data)) || bar.Get() != n || buf.String() != data {
+		t.Errorf("Unexpected copy result: %d, %v, bar %d", n, err, bar.Get())
+	}
+}
+
+func Test_ProxyReaderInterfaces(t *testing.T) {
+	bar := New(100)
+	for _, c := range []struct {
+		r                    io.Reader
+		isSeeker, isReaderAt bool
+	}{
+		{bytes.NewBufferString("data"), false, false},
+		{struct{ io.ReadSeeker }{strings.NewReader("data")}, true, false},
+		{io.NewSectionReader(strings.NewReader("data"), 0, 4), true, true},
+		{struct {
+			io.Reader
+			io.ReaderAt
+		}{strings.NewReader("data"), strings.NewReader("data")}, false, true},
+	} {
+		r := bar.WrapReader(c.r)
+		if _, ok := r.(io.Seeker); ok != c.isSeeker {
+			t.Errorf("Expected io.Seeker %v for %T", c.isSeeker, c.r)
+		}
+		if _, ok := r.(io.ReaderAt); ok != c.isReaderAt {
+			t.Errorf("Expected io.ReaderAt %v for %T", c.isReaderAt, c.r)
+		}
+	}
+
+	// WriteTo falls back to io.Copy
+	// NewProxyReader keeps returning *Reader without Seek and ReadAt
+	var r io.WriterTo = bar.NewProxyReader(struct{ io.Reader }{strings.NewReader("data")})
+	if _, ok := r.(io.Seeker); ok {
+		t.Error("Unexpected io.Seeker of *Reader")
+	}
+	buf := &bytes.Buffer{}
+	if n, err := r.WriteTo(buf); n != 4 || err != nil || bar.Get() != 4 {
+		t.Errorf("Unexpected WriteTo result: %d, %v, bar %d", n, err, bar.Get())
+	}
+}
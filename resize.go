@@ -0,0 +1,94 @@
+package pb
+
+import "sync"
+
+// resizeWatcher is the watcher of the terminal resize shared by the watched terminals,
+// it runs while there are terminals to watch
+var resizeWatcher struct {
+	sync.Mutex
+	terminals map[*Terminal]bool
+	stop      func()
+}
+
+// watchResize adds the terminal to the resize watcher and starts it with the first terminal
+func watchResize(t *Terminal) {
+	resizeWatcher.Lock()
+	defer resizeWatcher.Unlock()
+	if resizeWatcher.terminals == nil {
+		resizeWatcher.terminals = make(map[*Terminal]bool)
+	}
+	if len(resizeWatcher.terminals) == 0 {
+		resizeWatcher.stop = startResizeWatcher(updateTerminals)
+	}
+	resizeWatcher.terminals[t] = true
+}
+
+// unwatchResize removes the terminal from the resize watcher and stops it with the last terminal
+func unwatchResize(t *Terminal) {
+	resizeWatcher.Lock()
+	defer resizeWatcher.Unlock()
+	if !resizeWatcher.terminals[t] {
+		return
+	}
+	delete(resizeWatcher.terminals, t)
+	if len(resizeWatcher.terminals) == 0 {
+		resizeWatcher.stop()
+		resizeWatcher.stop = nil
+	}
+}
+
+// updateTerminals updates the size of the watched terminals
+func updateTerminals() {
+	resizeWatcher.Lock()
+	terminals := make([]*Terminal, 0, len(resizeWatcher.terminals))
+	for t := range resizeWatcher.terminals {
+		terminals = append(terminals, t)
+	}
+	resizeWatcher.Unlock()
+	for _, t := range terminals {
+		t.update()
+	}
+}
+
+// OnResize adds the function called with the new size after the terminal of the process is resized
+// Example: pb.OnResize(func(width, height int) { log.Printf("terminal is %dx%d", width, height) })
+func OnResize(f func(width, height int)) {
+	StdTerminal().OnResize(f)
+}
+
+// terminalWidth returns width of the terminal of the process.
+func terminalWidth() (int, error) {
+	return StdTerminal().Width()
+}
+
+// terminalHeight returns height of the terminal of the process.
+func terminalHeight() (int, error) {
+	return StdTerminal().Height()
+}
+
+// wrappedRows returns the number of terminal rows of the lines of the widths
+func wrappedRows(widths []int, termWidth int) (rows int) {
+	for _, w := range widths {
+		if termWidth > 0 && w > termWidth {
+			rows += (w + termWidth - 1) / termWidth
+		} else {
+			rows++
+		}
+	}
+	return
+}
+
+// redraw returns the start of the bar line of the width,
+// after the terminal is resized it clears all the rows of the previous line
+func (pb *ProgressBar) redraw(width int) string {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	termWidth, _, gen, err := pb.term().size()
+	last := pb.lastWidth
+	pb.lastWidth = width
+	if gen == pb.sizeGen || err != nil {
+		return "\r"
+	}
+	pb.sizeGen = gen
+	return clearRows(wrappedRows([]int{last}, termWidth) - 1)
+}
@@ -0,0 +1,33 @@
+// +build windows appengine
+
+package pb
+
+import "time"
+
+// Interval of the terminal size checks on the platforms without SIGWINCH
+const resizePollInterval = time.Second / 2
+
+// startResizeWatcher calls f every resizePollInterval until stop is called
+func startResizeWatcher(f func()) (stop func()) {
+	ticker := time.NewTicker(resizePollInterval)
+	done := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				f()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+// clearRows returns the carriage return, the console does not wrap the lines on resize
+func clearRows(above int) string {
+	return "\r"
+}
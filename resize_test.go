@@ -0,0 +1,67 @@
+// +build linux darwin freebsd netbsd openbsd solaris dragonfly
+
+package pb
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// resize simulates the resize of the terminal
+func resize(t *Terminal, width, height int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.valid, t.width, t.height, t.err = true, width, height, nil
+	t.gen++
+}
+
+func Test_WrappedRows(t *testing.T) {
+	if rows := wrappedRows([]int{10, 25, 0, 40}, 20); rows != 6 {
+		t.Errorf("Expected 6 rows, got %d", rows)
+	}
+	if rows := wrappedRows([]int{10, 25}, 0); rows != 2 {
+		t.Errorf("Expected 2 rows, got %d", rows)
+	}
+}
+
+func Test_BarRedrawAfterResize(t *testing.T) {
+	buf := &bytes.Buffer{}
+	bar := New(10).SetWidth(30).SetMode(MODE_TERMINAL)
+	bar.Output = buf
+	bar.Terminal = NewTerminal(os.Stdout)
+	bar.Set(1)
+	bar.Update()
+
+	resize(bar.Terminal, 12, 10)
+	buf.Reset()
+	bar.Set(2)
+	bar.Update()
+	// 30 columns are wrapped into 3 rows of the terminal
+	if out := buf.String(); !strings.HasPrefix(out, "\033[2A\r\033[J") {
+		t.Errorf("Expected clear of 3 rows, got %q", out)
+	}
+
+	buf.Reset()
+	bar.Set(3)
+	bar.Update()
+	if out := buf.String(); !strings.HasPrefix(out, "\r ") {
+		t.Errorf("Expected plain redraw, got %q", out)
+	}
+}
+
+func Test_PoolRedrawAfterResize(t *testing.T) {
+	first, second := New(10).Prefix("1"), New(10).Prefix("2")
+	pool, buf := newTestPool(first, second)
+	pool.Terminal = NewTerminal(os.Stdout)
+	pool.print(true)
+
+	resize(pool.Terminal, 15, 10)
+	buf.Reset()
+	pool.print(false)
+	// 2 lines of 20 columns are wrapped into 4 rows
+	if out := buf.String(); !strings.HasPrefix(out, "\033[4A\r\033[J\r1") {
+		t.Errorf("Expected clear of 4 rows, got %q", out)
+	}
+}
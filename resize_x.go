@@ -0,0 +1,41 @@
+// +build linux darwin freebsd netbsd openbsd solaris dragonfly
+// +build !appengine
+
+package pb
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// startResizeWatcher calls f on every SIGWINCH until stop is called
+func startResizeWatcher(f func()) (stop func()) {
+	sig := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sig, syscall.SIGWINCH)
+	go func() {
+		for {
+			select {
+			case <-sig:
+				f()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sig)
+		close(done)
+	}
+}
+
+// clearRows returns the sequence which moves the cursor to the first of the rows
+// above and below it and clears the screen from there
+func clearRows(above int) string {
+	if above > 0 {
+		return fmt.Sprintf("\033[%dA\r\033[J", above)
+	}
+	return "\r\033[J"
+}
@@ -6,7 +6,19 @@ import (
 )
 
 // Finds the control character sequences (like colors)
-var ctrlFinder = regexp.MustCompile("\x1b\x5b[0-9]+\x6d")
+// CSI: ESC [, parameter bytes 0x30-0x3f, intermediate bytes 0x20-0x2f and final byte 0x40-0x7e
+var ctrlFinder = regexp.MustCompile("\x1b\\[[\x30-\x3f]*[\x20-\x2f]*[\x40-\x7e]")
+
+// truncate cuts the plain text to the width with an ellipsis
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if runewidth.StringWidth(s) <= width {
+		return s
+	}
+	return runewidth.Truncate(s, width, "…")
+}
 
 func escapeAwareRuneCountInString(s string) int {
 	n := runewidth.StringWidth(s)
@@ -18,3 +18,15 @@ func Test_RuneCount(t *testing.T) {
 		t.Errorf("Invalid length %d, expected %d", l, e)
 	}
 }
+
+func Test_RuneCountCSI(t *testing.T) {
+	for _, s := range []string{
+		"\x1b[1;32mHello\x1b[0m",
+		"\x1b[38;5;208mHello\x1b[m",
+		"\x1b[38;2;1;2;3mHel\x1b[Klo",
+	} {
+		if e, l := 5, escapeAwareRuneCountInString(s); l != e {
+			t.Errorf("Invalid length %d of %q, expected %d", l, s, e)
+		}
+	}
+}
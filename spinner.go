@@ -0,0 +1,28 @@
+package pb
+
+// Spinner frames for bars with unknown total
+var (
+	SpinnerDots    = []string{".  ", ".. ", "...", " ..", "  .", "   "}
+	SpinnerLine    = []string{"-", "\\", "|", "/"}
+	SpinnerBraille = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+)
+
+// Set spinner frames, the spinner is shown instead of the bar while total is unknown
+// Frames change on every refresh, even when the value doesn't change
+// Example: bar.SetSpinner(pb.SpinnerBraille)
+// Example: bar.SetSpinner([]string{"◐", "◓", "◑", "◒"})
+func (pb *ProgressBar) SetSpinner(frames []string) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.Spinner = frames
+	return pb
+}
+
+// spinnerText returns the spinner frame for the state, SpinnerLine is used by default
+func (pb *ProgressBar) spinnerText(s *State) string {
+	frames := pb.Spinner
+	if len(frames) == 0 {
+		frames = SpinnerLine
+	}
+	return frames[s.Tick%len(frames)]
+}
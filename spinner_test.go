@@ -0,0 +1,50 @@
+package pb
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_SpinnerFrames(t *testing.T) {
+	bar := New(0).SetSpinner(SpinnerLine)
+	for tick, expected := range []string{"-", "\\", "|", "/", "-"} {
+		if frame := bar.spinnerText(&State{Tick: tick}); frame != expected {
+			t.Errorf("Tick %d: expected %q, got %q", tick, expected, frame)
+		}
+	}
+}
+
+func Test_SpinnerInsteadOfBar(t *testing.T) {
+	bar := New(0).SetSpinner([]string{"a", "b"}).SetWidth(20)
+	bar.ShowPercent = false
+	s := &State{Current: 5, Width: 20, Tick: 1}
+	if out := bar.renderDefault(s); out != " 5 / ? b" {
+		t.Errorf("Expected %q, got %q", " 5 / ? b", out)
+	}
+}
+
+func Test_UnknownTotalMovesWithTime(t *testing.T) {
+	bar := New(0)
+	s := &State{Current: 5}
+	var boxes []string
+	for tick := 0; tick < 3; tick++ {
+		s.Tick = tick
+		boxes = append(boxes, bar.barBox(s, 5))
+	}
+	expected := []string{"[----=]", "[---=-]", "[--=--]"}
+	if strings.Join(boxes, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected %q, got %q", expected, boxes)
+	}
+}
+
+func Test_SpinnerNotStarted(t *testing.T) {
+	// the bar which is not started is drawn without the elapsed ticks since zero time
+	bar := New(0).SetWidth(40)
+	bar.NotPrint = true
+	bar.Update()
+	if s := bar.String(); !strings.HasSuffix(s, "-=]") {
+		t.Errorf("Expected the position of the first tick, got %q", s)
+	}
+	bar.SetSpinner(SpinnerDots)
+	bar.Finish()
+}
@@ -0,0 +1,196 @@
+package pb
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State is a snapshot of the bar passed to template elements
+type State struct {
+	Current   int64
+	Total     int64
+	Width     int
+	Elapsed   time.Duration
+	Speed     float64 // units per second from the bar's Estimator
+	Tick      int     // number of refreshes since start, for animations
+	Units     Units
+	Finished  bool
+	Cancelled bool
+	Err       error // error of the failed bar, see Fail
+	Paused    bool
+}
+
+// ElementFunc renders a template element for the given state
+// For example:
+// pb.RegisterElement("files", func(s *pb.State) string {
+//     return fmt.Sprintf("%d files", s.Current)
+// })
+//
+type ElementFunc func(s *State) string
+
+var (
+	elementsMu sync.RWMutex
+	elements   = make(map[string]ElementFunc)
+)
+
+// Register custom element, it can be used in templates as {{name}}
+// Built-in elements can't be overridden
+func RegisterElement(name string, fn ElementFunc) {
+	elementsMu.Lock()
+	defer elementsMu.Unlock()
+	elements[name] = fn
+}
+
+func lookupElement(name string) (fn ElementFunc, ok bool) {
+	elementsMu.RLock()
+	defer elementsMu.RUnlock()
+	fn, ok = elements[name]
+	return
+}
+
+// templatePart is a literal string or a named element with optional width.
+// Positive width pads the element on the left, negative - on the right.
+type templatePart struct {
+	literal string
+	name    string
+	width   int
+}
+
+// parseTemplate splits template into literals and {{name}} or {{name width}} elements
+func parseTemplate(tmpl string) (parts []templatePart) {
+	for len(tmpl) > 0 {
+		start := strings.Index(tmpl, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(tmpl[start:], "}}")
+		if end < 0 {
+			break
+		}
+		if start > 0 {
+			parts = append(parts, templatePart{literal: tmpl[:start]})
+		}
+		fields := strings.Fields(tmpl[start+2 : start+end])
+		tmpl = tmpl[start+end+2:]
+		if len(fields) == 0 {
+			continue
+		}
+		part := templatePart{name: fields[0]}
+		if len(fields) > 1 {
+			part.width, _ = strconv.Atoi(fields[1])
+		}
+		parts = append(parts, part)
+	}
+	if len(tmpl) > 0 {
+		parts = append(parts, templatePart{literal: tmpl})
+	}
+	return
+}
+
+// padElement pads s with spaces up to abs(width)
+func padElement(s string, width int) string {
+	left := width > 0
+	if width < 0 {
+		width = -width
+	}
+	n := width - escapeAwareRuneCountInString(s)
+	if n <= 0 {
+		return s
+	}
+	if left {
+		return strings.Repeat(" ", n) + s
+	}
+	return s + strings.Repeat(" ", n)
+}
+
+// element renders built-in or registered element by name
+func (pb *ProgressBar) element(name string, s *State) string {
+	switch name {
+	case "prefix":
+		return pb.prefix
+	case "postfix":
+		return pb.postfix
+	case "message":
+		text, _ := pb.messageText(s)
+		return text
+	case "counters":
+		return pb.countersText(s)
+	case "percent":
+		return pb.percentText(s)
+	case "speed":
+		return pb.speedText(s)
+	case "spinner":
+		return pb.spinnerText(s)
+	case "etime":
+		return pb.timeLeftText(s)
+	case "elapsed":
+		return Format(int64((s.Elapsed / time.Second) * time.Second)).To(U_DURATION).String()
+	}
+	if fn, ok := lookupElement(name); ok {
+		return fn(s)
+	}
+	return ""
+}
+
+// renderTemplate draws the template, the {{bar}} elements without width
+// share the space left after the other elements are measured
+func (pb *ProgressBar) renderTemplate(parts []templatePart, s *State) string {
+	texts := make([]string, len(parts))
+	var used, bars, messages int
+	for i, p := range parts {
+		switch {
+		case p.name == "":
+			texts[i] = p.literal
+		case p.name == "bar" && p.width == 0:
+			bars++
+			continue
+		case p.name == "message" && p.width == 0:
+			messages++
+			continue
+		case p.name == "message":
+			text, style := pb.messageText(s)
+			texts[i] = padElement(pb.styled(style, truncate(text, abs(p.width))), p.width)
+		case p.name == "bar":
+			texts[i] = pb.barBox(s, abs(p.width)-escapeAwareRuneCountInString(pb.BarStart+pb.BarEnd))
+		default:
+			texts[i] = padElement(pb.styled(p.name, pb.element(p.name, s)), p.width)
+		}
+		used += escapeAwareRuneCountInString(texts[i])
+	}
+	if messages > 0 {
+		// messages take the space left after MIN_BAR_WIDTH of the bars
+		message, style := pb.messageText(s)
+		if s.Width > 0 {
+			free := s.Width - used
+			if bars > 0 {
+				free -= bars * (MIN_BAR_WIDTH + escapeAwareRuneCountInString(pb.BarStart+pb.BarEnd))
+			}
+			message = truncate(message, free/messages)
+		}
+		for i, p := range parts {
+			if p.name == "message" && p.width == 0 {
+				texts[i] = pb.styled(style, message)
+				used += escapeAwareRuneCountInString(texts[i])
+			}
+		}
+	}
+	if bars > 0 {
+		size := (s.Width - used) / bars
+		size -= escapeAwareRuneCountInString(pb.BarStart + pb.BarEnd)
+		for i, p := range parts {
+			if p.name == "bar" && p.width == 0 {
+				texts[i] = pb.barBox(s, size)
+			}
+		}
+	}
+	return strings.Join(texts, "")
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,87 @@
+package pb
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_ParseTemplate(t *testing.T) {
+	parts := parseTemplate("{{prefix}} [{{percent 8}}] {{bar}}{{broken")
+	expected := []templatePart{
+		{name: "prefix"},
+		{literal: " ["},
+		{name: "percent", width: 8},
+		{literal: "] "},
+		{name: "bar"},
+		{literal: "{{broken"},
+	}
+	if len(parts) != len(expected) {
+		t.Fatalf("Expected %d parts, got %d: %v", len(expected), len(parts), parts)
+	}
+	for i := range expected {
+		if parts[i] != expected[i] {
+			t.Errorf("Part %d: expected %v, got %v", i, expected[i], parts[i])
+		}
+	}
+}
+
+func Test_TemplateRender(t *testing.T) {
+	bar := New(100).SetWidth(30).SetTemplate("{{prefix}}|{{bar}}|{{percent -8}}").Prefix("p")
+	bar.NotPrint = true
+	bar.ManualUpdate = true
+	bar.Start()
+	bar.Set(50)
+	bar.Update()
+
+	expected := "p|[========>--------]|50.00%  "
+	if actual := bar.String(); actual != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
+
+func Test_TemplateCustomElement(t *testing.T) {
+	RegisterElement("test_files", func(s *State) string {
+		return fmt.Sprintf("%d of %d files", s.Current, s.Total)
+	})
+	bar := New(10).SetWidth(20).SetTemplate("{{test_files}} {{unknown}}")
+	bar.NotPrint = true
+	bar.ManualUpdate = true
+	bar.Start()
+	bar.Set(3)
+	bar.Update()
+
+	expected := "3 of 10 files       "
+	if actual := bar.String(); actual != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
+
+func Test_TemplateMessage(t *testing.T) {
+	bar := New(100).SetWidth(30).SetTemplate("{{bar}} {{message}}")
+	bar.NotPrint = true
+	bar.ManualUpdate = true
+	bar.Start()
+	bar.SetMessage("copying a_very_long_file_name.txt").Set(50)
+	bar.Update()
+
+	// the message is truncated to keep MIN_BAR_WIDTH of the bar
+	expected := "[====>-----] copying a_very_l…"
+	if actual := bar.String(); actual != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+
+	bar.SetTemplate("{{message -10}}|{{message 4}}|")
+	bar.SetMessage("ok").Set(51)
+	bar.Update()
+	expected = "ok        |  ok|"
+	if actual := bar.String(); !strings.HasPrefix(actual, expected) {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+	bar.SetMessage("copying file").Set(52)
+	bar.Update()
+	expected = "copying f…|cop…|"
+	if actual := bar.String(); !strings.HasPrefix(actual, expected) {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
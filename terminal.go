@@ -0,0 +1,261 @@
+package pb
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+var ErrPoolWasStarted = errors.New("Bar pool was started")
+
+// Terminal is the terminal of the bars: its size, echo control and isatty
+// The size is cached, it is updated when the terminal is resized
+type Terminal struct {
+	f *os.File
+
+	mu            sync.Mutex
+	watching      bool
+	released      bool
+	valid         bool
+	width, height int
+	err           error
+	// gen is incremented on every change of the size
+	gen      int
+	onResize []func(width, height int)
+
+	ttyOnce sync.Once
+	isTTY   bool
+
+	echoLocked bool
+	oldState   termState
+}
+
+// NewTerminal returns the terminal of the file
+// Example: pool.Terminal = pb.NewTerminal(os.Stderr)
+func NewTerminal(f *os.File) *Terminal {
+	return &Terminal{f: f}
+}
+
+var (
+	stdTerminal     *Terminal
+	stdTerminalOnce sync.Once
+
+	// terminals of the Output files with the number of their users,
+	// the files which are not terminals are kept too to check isatty once
+	fileTerminals   = make(map[*os.File]*fileTerminal)
+	fileTerminalsMu sync.Mutex
+)
+
+type fileTerminal struct {
+	t    *Terminal
+	refs int
+}
+
+// StdTerminal returns the terminal of the process, it is opened on first use
+// It's /dev/tty on unix (stdin if /dev/tty is not available) and stdout on windows
+func StdTerminal() *Terminal {
+	stdTerminalOnce.Do(func() {
+		stdTerminal = NewTerminal(openTerminal())
+	})
+	return stdTerminal
+}
+
+// acquireTerminal returns the shared terminal of the file, it must be released by releaseTerminal
+func acquireTerminal(f *os.File) *Terminal {
+	fileTerminalsMu.Lock()
+	defer fileTerminalsMu.Unlock()
+	ft, ok := fileTerminals[f]
+	if !ok {
+		ft = &fileTerminal{t: NewTerminal(f)}
+		fileTerminals[f] = ft
+	}
+	ft.refs++
+	return ft.t
+}
+
+// releaseTerminal releases the shared terminal of the file, the last user releases the terminal
+func releaseTerminal(f *os.File) {
+	fileTerminalsMu.Lock()
+	defer fileTerminalsMu.Unlock()
+	ft, ok := fileTerminals[f]
+	if !ok {
+		return
+	}
+	if ft.refs--; ft.refs == 0 {
+		delete(fileTerminals, f)
+		ft.t.Release()
+	}
+}
+
+// outputTerminal is the terminal of the Output file acquired by a bar or a pool
+type outputTerminal struct {
+	f        *os.File
+	t        *Terminal
+	released bool
+}
+
+// get returns the terminal of out if it is a terminal file, StdTerminal otherwise
+func (o *outputTerminal) get(out io.Writer) *Terminal {
+	if f, _ := out.(*os.File); f != o.f {
+		o.release()
+		o.f, o.t, o.released = f, nil, false
+		if f != nil {
+			o.t = acquireTerminal(f)
+		}
+	}
+	if o.t != nil && o.t.IsTerminal() {
+		return o.t
+	}
+	return StdTerminal()
+}
+
+// release releases the acquired terminal, it can be used after that without the resize watcher
+func (o *outputTerminal) release() {
+	if o.f != nil && !o.released {
+		o.released = true
+		releaseTerminal(o.f)
+	}
+}
+
+// isTerminalWriter returns true if w is a terminal file
+func isTerminalWriter(w io.Writer) bool {
+	f, ok := w.(interface {
+		Fd() uintptr
+	})
+	return ok && isTerminal(f.Fd())
+}
+
+// File returns the file of the terminal
+func (t *Terminal) File() *os.File {
+	return t.f
+}
+
+// IsTerminal returns true if the file is a terminal
+func (t *Terminal) IsTerminal() bool {
+	t.ttyOnce.Do(func() {
+		t.isTTY = isTerminal(t.f.Fd())
+	})
+	return t.isTTY
+}
+
+// Size returns width and height of the terminal
+func (t *Terminal) Size() (width, height int, err error) {
+	width, height, _, err = t.size()
+	return
+}
+
+// Width returns width of the terminal
+func (t *Terminal) Width() (int, error) {
+	width, _, _, err := t.size()
+	return width, err
+}
+
+// Height returns height of the terminal
+func (t *Terminal) Height() (int, error) {
+	_, height, _, err := t.size()
+	return height, err
+}
+
+// OnResize adds the function called with the new size after the terminal is resized
+func (t *Terminal) OnResize(f func(width, height int)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.watch()
+	t.onResize = append(t.onResize, f)
+}
+
+// Release stops watching the size of the terminal, the file is not closed
+// The size of the released terminal is read on every use
+func (t *Terminal) Release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.released, t.valid = true, false
+	if t.watching {
+		t.watching = false
+		unwatchResize(t)
+	}
+}
+
+// watch adds the terminal to the resize watcher, the files which are not terminals
+// and the released terminals are not watched
+// Must be called with t.mu held
+func (t *Terminal) watch() {
+	if !t.watching && !t.released && t.IsTerminal() {
+		t.watching = true
+		watchResize(t)
+	}
+}
+
+// size returns the cached size of the terminal and its generation
+func (t *Terminal) size() (width, height, gen int, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.watch()
+	if !t.valid {
+		t.width, t.height, t.err = terminalSize(t.f.Fd())
+		// the size of the released terminal is not updated by the watcher
+		t.valid = !t.released
+	}
+	return t.width, t.height, t.gen, t.err
+}
+
+// update reads the size of the terminal and calls the OnResize functions if it is changed
+func (t *Terminal) update() {
+	width, height, err := terminalSize(t.f.Fd())
+	t.mu.Lock()
+	changed := t.valid && (width != t.width || height != t.height)
+	t.width, t.height, t.err = width, height, err
+	t.valid = true
+	if changed {
+		t.gen++
+	}
+	onResize := append([]func(width, height int){}, t.onResize...)
+	t.mu.Unlock()
+	if changed && err == nil {
+		for _, f := range onResize {
+			f(width, height)
+		}
+	}
+}
+
+// LockEcho disables echo of the terminal input
+// The echo is restored by UnlockEcho, on a value from quit or on the exit signals
+func (t *Terminal) LockEcho() (quit chan int, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.echoLocked {
+		return nil, ErrPoolWasStarted
+	}
+	if err = lockEcho(t.f.Fd(), &t.oldState); err != nil {
+		return
+	}
+	t.echoLocked = true
+	quit = make(chan int, 1)
+	go t.catchTerminate(quit)
+	return
+}
+
+// UnlockEcho restores echo of the terminal input
+func (t *Terminal) UnlockEcho() (err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.echoLocked {
+		return
+	}
+	t.echoLocked = false
+	return unlockEcho(t.f.Fd(), &t.oldState)
+}
+
+// listen exit signals and restore terminal state
+func (t *Terminal) catchTerminate(quit chan int) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, terminateSignals...)
+	defer signal.Stop(sig)
+	select {
+	case <-quit:
+	case <-sig:
+	}
+	t.UnlockEcho()
+}
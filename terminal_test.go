@@ -0,0 +1,129 @@
+package pb
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func Test_TerminalOfFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "pb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	term := NewTerminal(f)
+	if term.File() != f || term.IsTerminal() {
+		t.Error("Expected the file which is not a terminal")
+	}
+	if _, _, err := term.Size(); err == nil {
+		t.Error("Expected error of the size")
+	}
+	if _, err := term.LockEcho(); err == nil {
+		t.Error("Expected error of the echo lock")
+	}
+	if err := term.UnlockEcho(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if term.watching {
+		t.Error("Expected the file which is not watched")
+	}
+}
+
+func Test_OutputTerminal(t *testing.T) {
+	f, err := ioutil.TempFile("", "pb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	refs := func() int {
+		fileTerminalsMu.Lock()
+		defer fileTerminalsMu.Unlock()
+		if ft, ok := fileTerminals[f]; ok {
+			return ft.refs
+		}
+		return 0
+	}
+
+	// the terminal of the file is shared and checked once
+	first, second := New(10), New(10)
+	first.Output, second.Output = f, f
+	if first.term() != StdTerminal() || second.term() != StdTerminal() || refs() != 2 {
+		t.Fatalf("Expected StdTerminal and 2 users of the file, got %d", refs())
+	}
+	if first.outputTerminal.t != second.outputTerminal.t {
+		t.Error("Expected the shared terminal of the file")
+	}
+	first.term()
+	if refs() != 2 {
+		t.Errorf("Expected 2 users of the file, got %d", refs())
+	}
+
+	// the terminal is released by the last finished bar
+	first.NotPrint, second.NotPrint = true, true
+	first.Finish()
+	first.Finish()
+	if refs() != 1 {
+		t.Errorf("Expected 1 user of the file, got %d", refs())
+	}
+	second.Finish()
+	if refs() != 0 || !second.outputTerminal.t.released {
+		t.Errorf("Expected released terminal, got %d users", refs())
+	}
+}
+
+func Test_BarTerminal(t *testing.T) {
+	f, err := ioutil.TempFile("", "pb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// the output files which are not terminals use the terminal of the process
+	bar := New(10)
+	for _, out := range []io.Writer{nil, &bytes.Buffer{}, f} {
+		if bar.Output = out; bar.term() != StdTerminal() {
+			t.Errorf("Expected StdTerminal for %T", out)
+		}
+	}
+
+	term := NewTerminal(f)
+	term.valid, term.width = true, 30
+	bar.Terminal = term
+	if w := bar.GetWidth(); w != 30 {
+		t.Errorf("Expected width of the terminal 30, got %d", w)
+	}
+}
+
+func Test_ResizeWatcher(t *testing.T) {
+	watched := func() int {
+		resizeWatcher.Lock()
+		defer resizeWatcher.Unlock()
+		return len(resizeWatcher.terminals)
+	}
+	// the terminals of the process can be watched already
+	n := watched()
+	first, second := NewTerminal(os.Stdout), NewTerminal(os.Stdout)
+	watchResize(first)
+	watchResize(second)
+	unwatchResize(first)
+	if watched() != n+1 {
+		t.Errorf("Expected %d watched terminals, got %d", n+1, watched())
+	}
+	unwatchResize(second)
+	unwatchResize(second)
+	if watched() != n {
+		t.Errorf("Expected %d watched terminals, got %d", n, watched())
+	}
+	resizeWatcher.Lock()
+	defer resizeWatcher.Unlock()
+	if (resizeWatcher.stop == nil) != (n == 0) {
+		t.Error("Expected the watcher running only with the watched terminals")
+	}
+}
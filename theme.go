@@ -0,0 +1,70 @@
+package pb
+
+import "math"
+
+// Theme is a set of the bar cells
+// Partials are the cells of the partially filled position, from the smallest one,
+// they increase the precision of the bar len(Partials)+1 times
+type Theme struct {
+	Start, Fill, Head, Empty, End string
+	Partials                      []string
+}
+
+// Preset themes
+var (
+	ThemeASCII  = Theme{Start: "[", Fill: "=", Head: ">", Empty: "-", End: "]"}
+	ThemeBlocks = Theme{Start: "│", Fill: "█", Head: "█", Empty: " ", End: "│",
+		Partials: []string{"▏", "▎", "▍", "▌", "▋", "▊", "▉"}}
+	ThemeShades = Theme{Start: "│", Fill: "█", Head: "█", Empty: "░", End: "│",
+		Partials: []string{"▒", "▓"}}
+	ThemePipes = Theme{Start: "[", Fill: "|", Head: "|", Empty: " ", End: "]"}
+)
+
+// Set theme of the bar, it replaces Format
+// Example: bar.SetTheme(pb.ThemeBlocks)
+func (pb *ProgressBar) SetTheme(t Theme) *ProgressBar {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.BarStart = t.Start
+	pb.Current = t.Fill
+	pb.CurrentN = t.Head
+	pb.Empty = t.Empty
+	pb.BarEnd = t.End
+	pb.Partials = t.Partials
+	return pb
+}
+
+// fillCells returns the filled cells of the bar of the size cells
+// With partials the last cell shows the fraction of the position, otherwise it is the head
+func (pb *ProgressBar) fillCells(s *State, size int) []string {
+	if len(pb.Partials) > 0 {
+		steps := int64(len(pb.Partials) + 1)
+		// float64 ratio doesn't overflow with the big values
+		ratio := math.Max(0, math.Min(1, float64(s.Current)/float64(s.Total)))
+		units := int64(ratio * float64(int64(size)*steps))
+		fill := make([]string, units/steps, units/steps+1)
+		for i := range fill {
+			fill[i] = pb.Current
+		}
+		if rem := units % steps; rem > 0 {
+			fill = append(fill, pb.Partials[rem-1])
+		}
+		return fill
+	}
+
+	curCount := int(math.Ceil((float64(s.Current) / float64(s.Total)) * float64(size)))
+	if curCount > size {
+		curCount = size
+	}
+	if curCount < 0 {
+		curCount = 0
+	}
+	fill := make([]string, curCount)
+	for i := range fill {
+		fill[i] = pb.Current
+	}
+	if curCount < size && curCount > 0 {
+		fill[curCount-1] = pb.CurrentN
+	}
+	return fill
+}
@@ -0,0 +1,55 @@
+package pb
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_ThemeBlocksPrecision(t *testing.T) {
+	bar := New(320).SetTheme(ThemeBlocks)
+	for _, c := range []struct {
+		current int64
+		box     string
+	}{
+		{0, "│    │"},
+		{1, "│    │"},
+		{10, "│▏   │"},
+		{300, "│███▊│"},
+		{320, "│████│"},
+	} {
+		if box := bar.barBox(&State{Current: c.current, Total: 320}, 4); box != c.box {
+			t.Errorf("%d: expected %q, got %q", c.current, c.box, box)
+		}
+	}
+	// exabytes don't overflow
+	if box := bar.barBox(&State{Current: 1 << 61, Total: 1 << 62}, 4); box != "│██  │" {
+		t.Errorf("Expected half of the bar, got %q", box)
+	}
+}
+
+func Test_SetTheme(t *testing.T) {
+	bar := New(10).SetTheme(ThemePipes)
+	if box := bar.barBox(&State{Current: 5, Total: 10}, 4); box != "[||  ]" {
+		t.Errorf("Unexpected box %q", box)
+	}
+	// Format replaces the theme
+	bar.SetTheme(ThemeShades).Format("[=>-]")
+	if box := bar.barBox(&State{Current: 5, Total: 10}, 4); box != "[=>--]" {
+		t.Errorf("Unexpected box %q", box)
+	}
+}
+
+func Test_ThemeWidth(t *testing.T) {
+	for _, theme := range []Theme{ThemeASCII, ThemeBlocks, ThemeShades, ThemePipes} {
+		bar := New(7).SetTheme(theme).SetWidth(40)
+		bar.NotPrint = true
+		bar.Set(3)
+		bar.Update()
+		if w := escapeAwareRuneCountInString(bar.String()); w != 40 {
+			t.Errorf("Expected width 40, got %d: %q", w, bar.String())
+		}
+		if strings.Contains(bar.String(), "\n") {
+			t.Errorf("Unexpected new line in %q", bar.String())
+		}
+	}
+}
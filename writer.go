@@ -0,0 +1,145 @@
+package pb
+
+import (
+	"io"
+)
+
+// It's proxy writer, implement io.Writer
+// Only the bytes accepted by the underlying writer are added to the bar
+type Writer struct {
+	io.Writer
+	bar *ProgressBar
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	n, err = w.Writer.Write(p)
+	w.bar.Add(n)
+	return
+}
+
+// ReadFrom uses io.ReaderFrom of the underlying writer when it implements one
+func (w *Writer) ReadFrom(r io.Reader) (n int64, err error) {
+	rf, ok := w.Writer.(io.ReaderFrom)
+	if !ok {
+		// hide ReadFrom to avoid recursion in io.Copy
+		return io.Copy(struct{ io.Writer }{w}, r)
+	}
+	cr := &countingReader{Reader: r, bar: w.bar}
+	n, err = rf.ReadFrom(cr)
+	// read bytes are counted for live progress, fix it up to the written ones
+	w.bar.Add64(n - cr.n)
+	return
+}
+
+// optional interfaces of the underlying writer, passed through by the proxy writer
+type (
+	flusher     interface{ Flush() error } // like bufio.Writer
+	httpFlusher interface{ Flush() }       // like http.Flusher
+	syncer      interface{ Sync() error }  // like os.File
+)
+
+// proxy writers of the combinations of io.Closer, flusher, httpFlusher and syncer
+type (
+	closeWriter struct {
+		*Writer
+		io.Closer
+	}
+	flushWriter struct {
+		*Writer
+		flusher
+	}
+	httpFlushWriter struct {
+		*Writer
+		httpFlusher
+	}
+	syncWriter struct {
+		*Writer
+		syncer
+	}
+	closeFlushWriter struct {
+		*Writer
+		io.Closer
+		flusher
+	}
+	closeHTTPFlushWriter struct {
+		*Writer
+		io.Closer
+		httpFlusher
+	}
+	closeSyncWriter struct {
+		*Writer
+		io.Closer
+		syncer
+	}
+	flushSyncWriter struct {
+		*Writer
+		flusher
+		syncer
+	}
+	httpFlushSyncWriter struct {
+		*Writer
+		httpFlusher
+		syncer
+	}
+	closeFlushSyncWriter struct {
+		*Writer
+		io.Closer
+		flusher
+		syncer
+	}
+	closeHTTPFlushSyncWriter struct {
+		*Writer
+		io.Closer
+		httpFlusher
+		syncer
+	}
+)
+
+// newProxyWriter returns the proxy writer with Close, Flush and Sync of w,
+// only when w implements them
+func newProxyWriter(w io.Writer, bar *ProgressBar) io.Writer {
+	pw := &Writer{w, bar}
+	c, isCloser := w.(io.Closer)
+	f, isFlusher := w.(flusher)
+	hf, isHTTPFlusher := w.(httpFlusher)
+	s, isSyncer := w.(syncer)
+	switch {
+	case isCloser && isFlusher && isSyncer:
+		return closeFlushSyncWriter{pw, c, f, s}
+	case isCloser && isHTTPFlusher && isSyncer:
+		return closeHTTPFlushSyncWriter{pw, c, hf, s}
+	case isCloser && isFlusher:
+		return closeFlushWriter{pw, c, f}
+	case isCloser && isHTTPFlusher:
+		return closeHTTPFlushWriter{pw, c, hf}
+	case isCloser && isSyncer:
+		return closeSyncWriter{pw, c, s}
+	case isFlusher && isSyncer:
+		return flushSyncWriter{pw, f, s}
+	case isHTTPFlusher && isSyncer:
+		return httpFlushSyncWriter{pw, hf, s}
+	case isCloser:
+		return closeWriter{pw, c}
+	case isFlusher:
+		return flushWriter{pw, f}
+	case isHTTPFlusher:
+		return httpFlushWriter{pw, hf}
+	case isSyncer:
+		return syncWriter{pw, s}
+	}
+	return pw
+}
+
+// countingReader adds read bytes to the bar and remembers their number
+type countingReader struct {
+	io.Reader
+	bar *ProgressBar
+	n   int64
+}
+
+func (r *countingReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	r.n += int64(n)
+	r.bar.Add(n)
+	return
+}
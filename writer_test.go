@@ -0,0 +1,113 @@
+package pb
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// shortWriter accepts at most max bytes and then fails
+type shortWriter struct {
+	max    int
+	closed bool
+}
+
+var errShortWriter = errors.New("short writer")
+
+func (w *shortWriter) Write(p []byte) (n int, err error) {
+	if len(p) > w.max {
+		n, err = w.max, errShortWriter
+	} else {
+		n = len(p)
+	}
+	w.max -= n
+	return
+}
+
+func (w *shortWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func Test_ProxyWriterCountsWritten(t *testing.T) {
+	bar := New(100)
+	sw := &shortWriter{max: 7}
+	w := bar.NewProxyWriter(sw)
+
+	n, err := w.Write([]byte("0123456789"))
+	if n != 7 || err != errShortWriter {
+		t.Errorf("Expected 7, %v; got %d, %v", errShortWriter, n, err)
+	}
+	if bar.Get() != 7 {
+		t.Errorf("Expected bar value 7, got %d", bar.Get())
+	}
+	if err = w.(io.Closer).Close(); err != nil || !sw.closed {
+		t.Errorf("Expected closed writer, got %v", err)
+	}
+}
+
+func Test_ProxyWriterCopy(t *testing.T) {
+	data := strings.Repeat("data", 10000)
+
+	// bytes.Buffer implements io.ReaderFrom
+	bar := New(len(data))
+	buf := &bytes.Buffer{}
+	n, err := io.Copy(bar.NewProxyWriter(buf), strings.NewReader(data))
+	if err != nil || n != int64(len(data)) || bar.Get() != n || buf.String() != data {
+		t.Errorf("Unexpected copy result: %d, %v, bar %d", n, err, bar.Get())
+	}
+
+	// without io.ReaderFrom, only written bytes are counted
+	bar = New(len(data))
+	n, err = io.Copy(bar.NewProxyWriter(&shortWriter{max: 100}), strings.NewReader(data))
+	if err != errShortWriter || n != 100 || bar.Get() != 100 {
+		t.Errorf("Unexpected copy result: %d, %v, bar %d", n, err, bar.Get())
+	}
+}
+
+func Test_ProxyWriterFlush(t *testing.T) {
+	bar := New(10)
+	buf := &bytes.Buffer{}
+	w := bar.NewProxyWriter(bufio.NewWriter(buf))
+	w.Write([]byte("hello"))
+	if buf.Len() != 0 {
+		t.Fatalf("Expected buffered data, got %q", buf.String())
+	}
+	if err := w.(flusher).Flush(); err != nil || buf.String() != "hello" {
+		t.Errorf("Expected flushed data, got %q, %v", buf.String(), err)
+	}
+}
+
+func Test_ProxyWriterInterfaces(t *testing.T) {
+	bar := New(10)
+	for _, c := range []struct {
+		w                                     io.Writer
+		isCloser, isFlusher, isHTTP, isSyncer bool
+	}{
+		{&bytes.Buffer{}, false, false, false, false},
+		{&shortWriter{}, true, false, false, false},
+		{bufio.NewWriter(&bytes.Buffer{}), false, true, false, false},
+		{httptest.NewRecorder(), false, false, true, false},
+		{os.Stderr, true, false, false, true},
+	} {
+		w := bar.NewProxyWriter(c.w)
+		if _, ok := w.(io.Closer); ok != c.isCloser {
+			t.Errorf("Expected io.Closer %v for %T", c.isCloser, c.w)
+		}
+		if _, ok := w.(flusher); ok != c.isFlusher {
+			t.Errorf("Expected Flush() error %v for %T", c.isFlusher, c.w)
+		}
+		if _, ok := w.(http.Flusher); ok != c.isHTTP {
+			t.Errorf("Expected http.Flusher %v for %T", c.isHTTP, c.w)
+		}
+		if _, ok := w.(syncer); ok != c.isSyncer {
+			t.Errorf("Expected Sync() error %v for %T", c.isSyncer, c.w)
+		}
+	}
+}